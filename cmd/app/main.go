@@ -41,13 +41,16 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	// Configuration and MongoDB client setup
-	mongoClient, err := config.Config(ctx) // Assuming this is a function that configures and connects a MongoDB client
+	// Configuration and store setup
+	s, err := config.Config(ctx) // Opens the backend selected by STORE_BACKEND
 	if err != nil {
 		log.Fatalf("Error: %s", err)
 	}
 
-	handler.InitRoutes(e, mongoClient) // Setup your routes, assuming you have a function to do this
+	if err := handler.InitRoutes(e, s); err != nil { // Setup your routes
+		_ = s.Close(ctx)
+		log.Fatalf("Error: %s", err)
+	}
 
 	go handler.StartBroadcasting()
 
@@ -71,8 +74,8 @@ func main() {
 	}
 	e.Logger.Info("Server gracefully stopped")
 
-	// Don't forget to disconnect the MongoDB client
-	if err := mongoClient.Disconnect(ctx); err != nil {
-		log.Printf("Error disconnecting MongoDB: %v", err)
+	// Don't forget to close the store
+	if err := s.Close(ctx); err != nil {
+		log.Printf("Error closing store: %v", err)
 	}
 }
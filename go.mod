@@ -7,6 +7,7 @@ require go.mongodb.org/mongo-driver v1.14.0
 require (
 	github.com/joho/godotenv v1.5.1
 	github.com/labstack/echo/v4 v4.11.4
+	github.com/mattn/go-sqlite3 v1.14.22
 )
 
 require (
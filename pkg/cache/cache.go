@@ -0,0 +1,335 @@
+package cache
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Options configures a Cache. Zero values disable the corresponding bound.
+type Options[V any] struct {
+	// TTL is how long entries stored with Set stay valid.
+	TTL time.Duration
+	// MaxEntries bounds the number of entries; the least recently used entry
+	// is evicted first.
+	MaxEntries int
+	// MaxBytes bounds the total Size of all entries. Values larger than the
+	// whole budget are not cached at all.
+	MaxBytes int64
+	// Size estimates the size of a value in bytes. Required with MaxBytes.
+	Size func(V) int64
+	// Now returns the current time for expiry; time.Now when nil.
+	Now func() time.Time
+}
+
+// ErrLoaderPanicked is returned to callers waiting on a GetOrLoad whose
+// loader panicked.
+var ErrLoaderPanicked = errors.New("cache: loader panicked")
+
+// Stats is a snapshot of a cache's counters.
+type Stats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
+	// Shared counts GetOrLoad misses that waited for a load already in flight
+	// instead of starting their own.
+	Shared  uint64
+	Entries int
+	Bytes   int64
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	size    int64
+	expires time.Time // zero means never
+}
+
+// call is a GetOrLoad load in flight for one key.
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+	// stale is set when the key is deleted while the load runs, so its
+	// possibly outdated result is handed to the waiters but not cached.
+	stale bool
+}
+
+// Cache is a concurrency-safe LRU cache with optional per-entry expiry.
+type Cache[K comparable, V any] struct {
+	opts Options[V]
+
+	mu    sync.Mutex
+	lru   *list.List // front is most recently used
+	items map[K]*list.Element
+	bytes int64
+
+	inflight map[K]*call[V]
+
+	// removed is told about every key removed from the cache, with c.mu
+	// held; see onRemove.
+	removed func(K)
+
+	hits, misses, evictions, expirations, shared atomic.Uint64
+}
+
+// New creates an empty Cache.
+func New[K comparable, V any](opts Options[V]) *Cache[K, V] {
+	return &Cache[K, V]{
+		opts:     opts,
+		lru:      list.New(),
+		items:    make(map[K]*list.Element),
+		inflight: make(map[K]*call[V]),
+	}
+}
+
+// Get returns the value stored under key if it is present and not expired.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(key)
+}
+
+// GetOrLoad returns the value stored under key, calling load to produce and
+// store it on a miss. Concurrent misses for the same key share a single call
+// to load and all receive its result or error; errors are not cached.
+func (c *Cache[K, V]) GetOrLoad(key K, load func() (V, error)) (V, error) {
+	c.mu.Lock()
+	if value, ok := c.get(key); ok {
+		c.mu.Unlock()
+		return value, nil
+	}
+	if cl, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		c.shared.Add(1)
+		<-cl.done
+		return cl.value, cl.err
+	}
+	cl := &call[V]{done: make(chan struct{})}
+	c.inflight[key] = cl
+	c.mu.Unlock()
+
+	c.load(key, cl, load)
+	return cl.value, cl.err
+}
+
+// load runs a GetOrLoad loader and publishes its result, even if it panics.
+func (c *Cache[K, V]) load(key K, cl *call[V], load func() (V, error)) {
+	returned := false
+	defer func() {
+		if !returned {
+			cl.err = ErrLoaderPanicked
+		}
+		var size int64
+		if cl.err == nil {
+			size = c.size(cl.value)
+		}
+
+		c.mu.Lock()
+		if c.inflight[key] == cl {
+			delete(c.inflight, key)
+		}
+		if cl.err == nil && !cl.stale && c.fits(size) {
+			c.set(key, cl.value, size, c.opts.TTL)
+		}
+		c.mu.Unlock()
+		close(cl.done)
+	}()
+
+	cl.value, cl.err = load()
+	returned = true
+}
+
+// get looks up key; c.mu must be held.
+func (c *Cache[K, V]) get(key K) (V, bool) {
+	var zero V
+	el, ok := c.items[key]
+	if !ok {
+		c.misses.Add(1)
+		return zero, false
+	}
+	e := el.Value.(*entry[K, V])
+	if !e.expires.IsZero() && c.now().After(e.expires) {
+		c.remove(el)
+		c.expirations.Add(1)
+		c.misses.Add(1)
+		return zero, false
+	}
+	c.lru.MoveToFront(el)
+	c.hits.Add(1)
+	return e.value, true
+}
+
+// Set stores value under key with the cache's default TTL.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.opts.TTL)
+}
+
+// SetWithTTL stores value under key, expiring after ttl (never if ttl <= 0).
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	size := c.size(value)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.fits(size) {
+		c.delete(key)
+		return
+	}
+	c.set(key, value, size, ttl)
+}
+
+// set stores an entry and evicts down to the budget; c.mu must be held.
+func (c *Cache[K, V]) set(key K, value V, size int64, ttl time.Duration) {
+	var expires time.Time
+	if ttl > 0 {
+		expires = c.now().Add(ttl)
+	}
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	c.items[key] = c.lru.PushFront(&entry[K, V]{key: key, value: value, size: size, expires: expires})
+	c.bytes += size
+
+	for c.overBudget() {
+		c.remove(c.lru.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// Delete removes key from the cache. A GetOrLoad in flight for key still
+// completes, but its result is not stored since it may predate the deletion.
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.delete(key)
+}
+
+// delete removes key and detaches any load in flight for it; c.mu must be held.
+func (c *Cache[K, V]) delete(key K) {
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	if cl, ok := c.inflight[key]; ok {
+		cl.stale = true
+		delete(c.inflight, key)
+	}
+}
+
+// Purge removes every entry. Loads in flight still complete, but their
+// results are not stored.
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.removed != nil {
+		for key := range c.items {
+			c.removed(key)
+		}
+	}
+	c.lru.Init()
+	c.items = make(map[K]*list.Element)
+	c.bytes = 0
+	for key, cl := range c.inflight {
+		cl.stale = true
+		delete(c.inflight, key)
+	}
+}
+
+// onRemove registers f to be called with every key that leaves the cache,
+// whether deleted, replaced, evicted or expired. f runs with c.mu held, so it
+// must not call back into the cache.
+func (c *Cache[K, V]) onRemove(f func(K)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removed = f
+}
+
+// has reports whether an entry is stored under key, expired or not, without
+// counting a hit or a miss.
+func (c *Cache[K, V]) has(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.items[key]
+	return ok
+}
+
+// Len returns the number of entries, including expired ones not yet reclaimed.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	entries, bytes := c.lru.Len(), c.bytes
+	c.mu.Unlock()
+
+	return Stats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+		Shared:      c.shared.Load(),
+		Entries:     entries,
+		Bytes:       bytes,
+	}
+}
+
+func (c *Cache[K, V]) now() time.Time {
+	if c.opts.Now != nil {
+		return c.opts.Now()
+	}
+	return time.Now()
+}
+
+// size returns the byte cost of value, or 0 when there is no byte budget.
+func (c *Cache[K, V]) size(value V) int64 {
+	if c.opts.MaxBytes <= 0 || c.opts.Size == nil {
+		return 0
+	}
+	return c.opts.Size(value)
+}
+
+// fits reports whether an entry of the given size can be cached at all.
+func (c *Cache[K, V]) fits(size int64) bool {
+	return c.opts.MaxBytes <= 0 || size <= c.opts.MaxBytes
+}
+
+// overBudget reports whether an entry must be evicted; c.mu must be held.
+func (c *Cache[K, V]) overBudget() bool {
+	if c.lru.Len() == 0 {
+		return false
+	}
+	if c.opts.MaxEntries > 0 && c.lru.Len() > c.opts.MaxEntries {
+		return true
+	}
+	return c.opts.MaxBytes > 0 && c.bytes > c.opts.MaxBytes
+}
+
+// remove unlinks an entry; c.mu must be held.
+func (c *Cache[K, V]) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*entry[K, V])
+	delete(c.items, e.key)
+	c.bytes -= e.size
+	if c.removed != nil {
+		c.removed(e.key)
+	}
+}
+
+// JSONSize estimates the size of a value as the length of its JSON encoding,
+// which is what the cached API responses end up costing on the wire.
+func JSONSize[V any](value V) int64 {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return int64(len(b))
+}
@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// clock is a manually advanced time source for Options.Now.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newClock() *clock {
+	return &clock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// step is one operation on a cache under test. Gets check that the key is
+// present with value, or absent if value is "".
+type step struct {
+	op    string // "set", "setTTL", "get", "delete", "purge" or "advance"
+	key   string
+	value string
+	d     time.Duration // TTL of setTTL, or time to advance
+}
+
+func set(key, value string) step { return step{op: "set", key: key, value: value} }
+
+func setTTL(key, value string, ttl time.Duration) step {
+	return step{op: "setTTL", key: key, value: value, d: ttl}
+}
+
+func get(key, value string) step   { return step{op: "get", key: key, value: value} }
+func del(key string) step          { return step{op: "delete", key: key} }
+func purge() step                  { return step{op: "purge"} }
+func advance(d time.Duration) step { return step{op: "advance", d: d} }
+
+func TestCache(t *testing.T) {
+	size := func(v string) int64 { return int64(len(v)) }
+	tests := []struct {
+		name  string
+		opts  Options[string]
+		steps []step
+		// keys lists the entries left at the end, most recently used first.
+		keys  []string
+		stats Stats
+	}{
+		{
+			name:  "hit before TTL",
+			opts:  Options[string]{TTL: time.Minute},
+			steps: []step{set("a", "1"), advance(time.Minute), get("a", "1")},
+			keys:  []string{"a"},
+			stats: Stats{Hits: 1, Entries: 1},
+		},
+		{
+			name:  "expired after TTL",
+			opts:  Options[string]{TTL: time.Minute},
+			steps: []step{set("a", "1"), advance(time.Minute + time.Nanosecond), get("a", "")},
+			stats: Stats{Misses: 1, Expirations: 1},
+		},
+		{
+			name:  "no TTL never expires",
+			steps: []step{set("a", "1"), advance(1000 * time.Hour), get("a", "1")},
+			keys:  []string{"a"},
+			stats: Stats{Hits: 1, Entries: 1},
+		},
+		{
+			name: "per-entry TTL overrides the default",
+			opts: Options[string]{TTL: time.Hour},
+			steps: []step{
+				setTTL("short", "1", time.Second), set("long", "2"),
+				advance(2 * time.Second), get("short", ""), get("long", "2"),
+			},
+			keys:  []string{"long"},
+			stats: Stats{Hits: 1, Misses: 1, Expirations: 1, Entries: 1},
+		},
+		{
+			name:  "set restarts the TTL",
+			opts:  Options[string]{TTL: time.Minute},
+			steps: []step{set("a", "1"), advance(50 * time.Second), set("a", "2"), advance(50 * time.Second), get("a", "2")},
+			keys:  []string{"a"},
+			stats: Stats{Hits: 1, Entries: 1},
+		},
+		{
+			name:  "evicts the least recently set",
+			opts:  Options[string]{MaxEntries: 2},
+			steps: []step{set("a", "1"), set("b", "2"), set("c", "3"), get("a", "")},
+			keys:  []string{"c", "b"},
+			stats: Stats{Misses: 1, Evictions: 1, Entries: 2},
+		},
+		{
+			name:  "get marks as recently used",
+			opts:  Options[string]{MaxEntries: 2},
+			steps: []step{set("a", "1"), set("b", "2"), get("a", "1"), set("c", "3"), get("b", "")},
+			keys:  []string{"c", "a"},
+			stats: Stats{Hits: 1, Misses: 1, Evictions: 1, Entries: 2},
+		},
+		{
+			name:  "overwrite does not evict",
+			opts:  Options[string]{MaxEntries: 2},
+			steps: []step{set("a", "1"), set("b", "2"), set("a", "3")},
+			keys:  []string{"a", "b"},
+			stats: Stats{Entries: 2},
+		},
+		{
+			name:  "byte budget evicts until it fits",
+			opts:  Options[string]{MaxBytes: 10, Size: size},
+			steps: []step{set("a", "1234"), set("b", "1234"), set("c", "123456")},
+			keys:  []string{"c", "b"},
+			stats: Stats{Evictions: 1, Entries: 2, Bytes: 10},
+		},
+		{
+			name:  "overwrite updates the byte count",
+			opts:  Options[string]{MaxBytes: 10, Size: size},
+			steps: []step{set("a", "1234"), set("a", "12")},
+			keys:  []string{"a"},
+			stats: Stats{Entries: 1, Bytes: 2},
+		},
+		{
+			name:  "value over the whole budget is not cached and drops the old one",
+			opts:  Options[string]{MaxBytes: 10, Size: size},
+			steps: []step{set("a", "1"), set("b", "12"), set("a", "12345678901"), get("a", "")},
+			keys:  []string{"b"},
+			stats: Stats{Misses: 1, Entries: 1, Bytes: 2},
+		},
+		{
+			name:  "delete",
+			opts:  Options[string]{MaxBytes: 10, Size: size},
+			steps: []step{set("a", "12"), set("b", "123"), del("a"), del("missing"), get("a", "")},
+			keys:  []string{"b"},
+			stats: Stats{Misses: 1, Entries: 1, Bytes: 3},
+		},
+		{
+			name:  "purge",
+			opts:  Options[string]{MaxBytes: 10, Size: size},
+			steps: []step{set("a", "12"), set("b", "123"), purge(), get("b", ""), set("c", "1")},
+			keys:  []string{"c"},
+			stats: Stats{Misses: 1, Entries: 1, Bytes: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := newClock()
+			tt.opts.Now = clk.Now
+			c := New[string, string](tt.opts)
+
+			for i, s := range tt.steps {
+				switch s.op {
+				case "set":
+					c.Set(s.key, s.value)
+				case "setTTL":
+					c.SetWithTTL(s.key, s.value, s.d)
+				case "get":
+					value, ok := c.Get(s.key)
+					if want := s.value != ""; ok != want || value != s.value {
+						t.Errorf("step %d: Get(%q) = %q, %v; want %q, %v", i, s.key, value, ok, s.value, want)
+					}
+				case "delete":
+					c.Delete(s.key)
+				case "purge":
+					c.Purge()
+				case "advance":
+					clk.Advance(s.d)
+				}
+			}
+
+			if keys := lruKeys(c); !slices.Equal(keys, tt.keys) {
+				t.Errorf("entries are %v, want %v", keys, tt.keys)
+			}
+			if stats := c.Stats(); stats != tt.stats {
+				t.Errorf("Stats() = %+v, want %+v", stats, tt.stats)
+			}
+			if c.Len() != len(tt.keys) {
+				t.Errorf("Len() = %d, want %d", c.Len(), len(tt.keys))
+			}
+		})
+	}
+}
+
+// lruKeys returns the keys of c, most recently used first.
+func lruKeys[V any](c *Cache[string, V]) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var keys []string
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		keys = append(keys, el.Value.(*entry[string, V]).key)
+	}
+	return keys
+}
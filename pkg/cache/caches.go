@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"dynamicrecipes/pkg/model"
+	"time"
+)
+
+// Ingredients caches ingredients by hex ID.
+var Ingredients = New[string, model.Ingredient](Options[model.Ingredient]{
+	TTL:        5 * time.Minute,
+	MaxEntries: 10000,
+})
+
+// Recipes caches resolved recipes by hex ID.
+var Recipes = New[string, model.Recipe](Options[model.Recipe]{
+	TTL:        5 * time.Minute,
+	MaxEntries: 10000,
+	MaxBytes:   32 << 20,
+	Size:       JSONSize[model.Recipe],
+})
+
+// Lists caches the ordered IDs behind the listing endpoints, under
+// "allIngredients" and "allRecipes". The entities themselves live in
+// Ingredients and Recipes.
+var Lists = New[string, []string](Options[[]string]{
+	TTL: 5 * time.Minute,
+})
+
+// IngredientDeps indexes Ingredients by the ingredient each entry holds.
+var IngredientDeps = NewDependencyIndex[string](Ingredients)
+
+// RecipeDeps indexes Recipes by the ingredients each recipe embeds.
+var RecipeDeps = NewDependencyIndex[string](Recipes)
+
+// SetIngredient caches an ingredient loaded at generation gen of IngredientDeps.
+func SetIngredient(gen uint64, ingredient model.Ingredient) {
+	id := ingredient.ObjectID.Hex()
+	IngredientDeps.Set(gen, id, ingredient, []string{id})
+}
+
+// SetRecipe caches a resolved recipe loaded at generation gen of RecipeDeps.
+func SetRecipe(gen uint64, recipe model.Recipe) {
+	deps := make([]string, 0, len(recipe.Ingredients))
+	for _, line := range recipe.Ingredients {
+		deps = append(deps, line.ObjectID.Hex())
+	}
+	RecipeDeps.Set(gen, recipe.ObjectID.Hex(), recipe, deps)
+}
+
+// InvalidateIngredient drops an ingredient and every cached recipe embedding it.
+func InvalidateIngredient(id string) {
+	IngredientDeps.Invalidate(id)
+	RecipeDeps.Invalidate(id)
+}
+
+// InvalidateRecipe drops a single recipe.
+func InvalidateRecipe(id string) {
+	RecipeDeps.Delete(id)
+}
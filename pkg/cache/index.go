@@ -0,0 +1,173 @@
+package cache
+
+import "sync"
+
+// maxChanges bounds the invalidations a DependencyIndex remembers. Once more
+// have accumulated they are forgotten at once, and loads that started before
+// are no longer cached.
+const maxChanges = 4096
+
+// DependencyIndex tracks which entries of a Cache were built from which
+// entities, so that a change to one entity invalidates exactly the entries
+// that embed it.
+//
+// Loaders take a Generation before reading from the database and pass it to
+// Set; if the key or one of its dependencies was invalidated in the meantime
+// the value may already be stale and is not cached. Invalidating one entity
+// does not keep loads of unrelated entries from being cached.
+type DependencyIndex[D comparable, K comparable, V any] struct {
+	cache *Cache[K, V]
+
+	mu    sync.Mutex
+	clock uint64
+	// floor is the clock when changedDeps and changedKeys were last
+	// cleared; Sets of generations before it are refused.
+	floor       uint64
+	changedDeps map[D]uint64 // clock of the last invalidation of each dependency
+	changedKeys map[K]uint64 // clock of the last deletion of each key
+	byDep       map[D]map[K]struct{}
+	byKey       map[K][]D
+
+	// removed queues keys the cache evicted or expired, to be untracked by
+	// the next write. The cache reports them under its own lock, which must
+	// not wait for mu.
+	removedMu sync.Mutex
+	removed   []K
+}
+
+// NewDependencyIndex creates an index over c. All writes to c should go
+// through the index from then on.
+func NewDependencyIndex[D comparable, K comparable, V any](c *Cache[K, V]) *DependencyIndex[D, K, V] {
+	x := &DependencyIndex[D, K, V]{
+		cache:       c,
+		changedDeps: make(map[D]uint64),
+		changedKeys: make(map[K]uint64),
+		byDep:       make(map[D]map[K]struct{}),
+		byKey:       make(map[K][]D),
+	}
+	c.onRemove(func(key K) {
+		x.removedMu.Lock()
+		x.removed = append(x.removed, key)
+		x.removedMu.Unlock()
+	})
+	return x
+}
+
+// Generation returns a token to pass to Set, marking when a load started.
+func (x *DependencyIndex[D, K, V]) Generation() uint64 {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.clock
+}
+
+// Set caches value under key as depending on deps, unless key or one of deps
+// was invalidated since gen was taken. It reports whether the value was
+// cached.
+func (x *DependencyIndex[D, K, V]) Set(gen uint64, key K, value V, deps []D) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.forgetRemoved()
+
+	if gen < x.floor || x.changedKeys[key] > gen {
+		return false
+	}
+	for _, dep := range deps {
+		if x.changedDeps[dep] > gen {
+			return false
+		}
+	}
+	x.untrack(key)
+	for _, dep := range deps {
+		keys, ok := x.byDep[dep]
+		if !ok {
+			keys = make(map[K]struct{})
+			x.byDep[dep] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	x.byKey[key] = deps
+	// Storing under x.mu keeps a concurrent Invalidate from running between
+	// the generation check and the write.
+	x.cache.Set(key, value)
+	return true
+}
+
+// Delete drops the entry cached under key.
+func (x *DependencyIndex[D, K, V]) Delete(key K) {
+	x.mu.Lock()
+	x.forgetRemoved()
+	x.tick()
+	x.changedKeys[key] = x.clock
+	x.untrack(key)
+	x.mu.Unlock()
+
+	x.cache.Delete(key)
+}
+
+// Invalidate drops every entry that depends on dep and returns their keys.
+func (x *DependencyIndex[D, K, V]) Invalidate(dep D) []K {
+	x.mu.Lock()
+	x.forgetRemoved()
+	x.tick()
+	x.changedDeps[dep] = x.clock
+	keys := make([]K, 0, len(x.byDep[dep]))
+	for key := range x.byDep[dep] {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		x.untrack(key)
+	}
+	x.mu.Unlock()
+
+	for _, key := range keys {
+		x.cache.Delete(key)
+	}
+	return keys
+}
+
+// Len returns the number of keys tracked.
+func (x *DependencyIndex[D, K, V]) Len() int {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.forgetRemoved()
+	return len(x.byKey)
+}
+
+// tick advances the clock for a change, first forgetting the changes
+// recorded so far if there are too many; x.mu must be held.
+func (x *DependencyIndex[D, K, V]) tick() {
+	if len(x.changedDeps)+len(x.changedKeys) >= maxChanges {
+		x.floor = x.clock + 1
+		x.changedDeps = make(map[D]uint64)
+		x.changedKeys = make(map[K]uint64)
+	}
+	x.clock++
+}
+
+// forgetRemoved untracks the keys the cache evicted or expired, unless they
+// have been set again since; x.mu must be held.
+func (x *DependencyIndex[D, K, V]) forgetRemoved() {
+	x.removedMu.Lock()
+	removed := x.removed
+	x.removed = nil
+	x.removedMu.Unlock()
+
+	for _, key := range removed {
+		// Sets go through x.mu, so a key present now was set after it
+		// was removed and is tracked correctly.
+		if !x.cache.has(key) {
+			x.untrack(key)
+		}
+	}
+}
+
+// untrack removes key from the index; x.mu must be held.
+func (x *DependencyIndex[D, K, V]) untrack(key K) {
+	for _, dep := range x.byKey[key] {
+		delete(x.byDep[dep], key)
+		if len(x.byDep[dep]) == 0 {
+			delete(x.byDep, dep)
+		}
+	}
+	delete(x.byKey, key)
+}
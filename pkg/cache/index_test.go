@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDependencyIndexInvalidate(t *testing.T) {
+	x := NewDependencyIndex[string](New[string, string](Options[string]{}))
+	gen := x.Generation()
+	x.Set(gen, "pancakes", "p", []string{"flour", "egg"})
+	x.Set(gen, "omelette", "o", []string{"egg"})
+	x.Set(gen, "bread", "b", []string{"flour", "yeast"})
+
+	keys := x.Invalidate("egg")
+	slices.Sort(keys)
+	if want := []string{"omelette", "pancakes"}; !slices.Equal(keys, want) {
+		t.Errorf("Invalidate(egg) = %v, want %v", keys, want)
+	}
+	for key, want := range map[string]bool{"pancakes": false, "omelette": false, "bread": true} {
+		if _, ok := x.cache.Get(key); ok != want {
+			t.Errorf("%s cached: %v, want %v", key, ok, want)
+		}
+	}
+	if keys := x.Invalidate("flour"); !slices.Equal(keys, []string{"bread"}) {
+		t.Errorf("Invalidate(flour) = %v, want [bread]", keys)
+	}
+	if x.Len() != 0 {
+		t.Errorf("%d keys still tracked", x.Len())
+	}
+}
+
+func TestDependencyIndexGenerations(t *testing.T) {
+	x := NewDependencyIndex[string](New[string, string](Options[string]{}))
+
+	// Loads started before a change are only refused if they depend on it.
+	gen := x.Generation()
+	x.Invalidate("flour")
+	x.Delete("cake")
+	tests := []struct {
+		key  string
+		deps []string
+		want bool
+	}{
+		{"pancakes", []string{"egg", "flour"}, false},
+		{"cake", []string{"sugar"}, false},
+		{"omelette", []string{"egg"}, true},
+		{"toast", nil, true},
+	}
+	for _, tt := range tests {
+		if got := x.Set(gen, tt.key, tt.key, tt.deps); got != tt.want {
+			t.Errorf("Set(%s) after the change = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+	if !x.Set(x.Generation(), "pancakes", "pancakes", []string{"egg", "flour"}) {
+		t.Error("a load started after the change was not cached")
+	}
+
+	// Forgetting old changes refuses every load that started before.
+	gen = x.Generation()
+	for i := 0; i < maxChanges; i++ {
+		x.Delete(fmt.Sprint("other ", i))
+	}
+	if x.Set(gen, "omelette", "omelette", []string{"egg"}) {
+		t.Error("a load older than the forgotten changes was cached")
+	}
+	if !x.Set(x.Generation(), "omelette", "omelette", []string{"egg"}) {
+		t.Error("a load started after the changes were forgotten was not cached")
+	}
+}
+
+func TestDependencyIndexForgetsRemovedKeys(t *testing.T) {
+	clk := newClock()
+	c := New[string, string](Options[string]{MaxEntries: 2, TTL: time.Minute, Now: clk.Now})
+	x := NewDependencyIndex[string](c)
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		x.Set(x.Generation(), key, key, []string{"flour"})
+	}
+	if x.Len() != 2 {
+		t.Errorf("%d keys tracked after evictions, want 2", x.Len())
+	}
+
+	clk.Advance(2 * time.Minute)
+	c.Get("c")
+	if x.Len() != 1 {
+		t.Errorf("%d keys tracked after an expiry, want 1", x.Len())
+	}
+
+	// A key set again after it was evicted stays tracked.
+	x.Set(x.Generation(), "a", "a", []string{"egg"})
+	x.Set(x.Generation(), "e", "e", []string{"yeast"})
+	x.Set(x.Generation(), "a", "a", []string{"egg"})
+	if keys := x.Invalidate("egg"); !slices.Equal(keys, []string{"a"}) {
+		t.Errorf("Invalidate(egg) = %v, want [a]", keys)
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("a is still cached after its dependency was invalidated")
+	}
+}
@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingLoader is a GetOrLoad loader that blocks until released and counts
+// its calls.
+type blockingLoader struct {
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+	value   string
+	err     error
+}
+
+func newBlockingLoader(value string, err error) *blockingLoader {
+	return &blockingLoader{started: make(chan struct{}, 16), release: make(chan struct{}), value: value, err: err}
+}
+
+func (l *blockingLoader) load() (string, error) {
+	l.calls.Add(1)
+	l.started <- struct{}{}
+	<-l.release
+	return l.value, l.err
+}
+
+type loadResult struct {
+	value string
+	err   error
+}
+
+// loadConcurrently starts n GetOrLoad calls for key and returns once one has
+// entered the loader and the others wait for it.
+func loadConcurrently(t *testing.T, c *Cache[string, string], key string, n int, l *blockingLoader) <-chan loadResult {
+	t.Helper()
+	results := make(chan loadResult, n)
+	shared := c.Stats().Shared
+	for i := 0; i < n; i++ {
+		go func() {
+			value, err := c.GetOrLoad(key, l.load)
+			results <- loadResult{value, err}
+		}()
+	}
+	<-l.started
+	waitFor(t, func() bool { return c.Stats().Shared-shared == uint64(n-1) })
+	return results
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGetOrLoadCoalescesMisses(t *testing.T) {
+	const n = 20
+	c := New[string, string](Options[string]{})
+	l := newBlockingLoader("recipes", nil)
+
+	results := loadConcurrently(t, c, "allRecipes", n, l)
+	close(l.release)
+	for i := 0; i < n; i++ {
+		if r := <-results; r.value != "recipes" || r.err != nil {
+			t.Errorf("GetOrLoad = %q, %v; want %q, nil", r.value, r.err, "recipes")
+		}
+	}
+
+	if calls := l.calls.Load(); calls != 1 {
+		t.Errorf("loader called %d times, want 1", calls)
+	}
+	if value, ok := c.Get("allRecipes"); !ok || value != "recipes" {
+		t.Errorf("Get after the load = %q, %v; want the loaded value", value, ok)
+	}
+	if value, err := c.GetOrLoad("allRecipes", l.load); value != "recipes" || err != nil || l.calls.Load() != 1 {
+		t.Errorf("GetOrLoad after the load = %q, %v and called the loader again", value, err)
+	}
+}
+
+func TestGetOrLoadSharesErrors(t *testing.T) {
+	const n = 10
+	errScan := errors.New("scan failed")
+	c := New[string, string](Options[string]{})
+	l := newBlockingLoader("", errScan)
+
+	results := loadConcurrently(t, c, "allIngredients", n, l)
+	close(l.release)
+	for i := 0; i < n; i++ {
+		if r := <-results; !errors.Is(r.err, errScan) {
+			t.Errorf("GetOrLoad error = %v, want %v", r.err, errScan)
+		}
+	}
+	if calls := l.calls.Load(); calls != 1 {
+		t.Errorf("loader called %d times, want 1", calls)
+	}
+
+	if _, ok := c.Get("allIngredients"); ok {
+		t.Error("the error result was cached")
+	}
+	value, err := c.GetOrLoad("allIngredients", func() (string, error) { return "ingredients", nil })
+	if value != "ingredients" || err != nil {
+		t.Errorf("GetOrLoad after a failed load = %q, %v; want a fresh load", value, err)
+	}
+}
+
+func TestGetOrLoadDeleteMarksLoadStale(t *testing.T) {
+	c := New[string, string](Options[string]{})
+	l := newBlockingLoader("old recipes", nil)
+
+	results := loadConcurrently(t, c, "allRecipes", 3, l)
+	c.Delete("allRecipes")
+
+	// A miss after the delete must not join the stale load.
+	fresh := make(chan loadResult, 1)
+	go func() {
+		value, err := c.GetOrLoad("allRecipes", func() (string, error) { return "new recipes", nil })
+		fresh <- loadResult{value, err}
+	}()
+	if r := <-fresh; r.value != "new recipes" || r.err != nil {
+		t.Errorf("GetOrLoad after Delete = %q, %v; want its own load", r.value, r.err)
+	}
+
+	close(l.release)
+	for i := 0; i < 3; i++ {
+		if r := <-results; r.value != "old recipes" || r.err != nil {
+			t.Errorf("waiters of the stale load got %q, %v; want its result", r.value, r.err)
+		}
+	}
+	if value, _ := c.Get("allRecipes"); value != "new recipes" {
+		t.Errorf("cached value is %q, want the load started after Delete", value)
+	}
+}
+
+func TestGetOrLoadPurgeMarksLoadStale(t *testing.T) {
+	c := New[string, string](Options[string]{})
+	l := newBlockingLoader("old recipes", nil)
+
+	results := loadConcurrently(t, c, "allRecipes", 1, l)
+	c.Purge()
+	close(l.release)
+	<-results
+
+	if _, ok := c.Get("allRecipes"); ok {
+		t.Error("a load in flight during Purge was cached")
+	}
+}
+
+func TestGetOrLoadPanic(t *testing.T) {
+	c := New[string, string](Options[string]{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() { _ = recover() }()
+		_, _ = c.GetOrLoad("allRecipes", func() (string, error) { panic("boom") })
+	}()
+	wg.Wait()
+
+	if _, ok := c.Get("allRecipes"); ok {
+		t.Error("a panicking load was cached")
+	}
+	value, err := c.GetOrLoad("allRecipes", func() (string, error) { return "recipes", nil })
+	if value != "recipes" || err != nil {
+		t.Errorf("GetOrLoad after a panic = %q, %v; want a fresh load", value, err)
+	}
+}
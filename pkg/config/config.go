@@ -1,9 +1,9 @@
 package config
 
-// TODO: Implement configuration logic
-
 import (
 	"context"
+	"dynamicrecipes/pkg/store"
+	"fmt"
 	"log"
 	"os"
 
@@ -12,13 +12,42 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func Config(ctx context.Context) (*mongo.Client, error) {
+// Config loads the environment and opens the store selected by STORE_BACKEND:
+// "mongo" (the default, using MONGODB_URI_STRING), "sqlite" (using the file at
+// SQLITE_PATH) or "memory" to run without a database.
+func Config(ctx context.Context) (store.Store, error) {
 	err := godotenv.Load()
 	if err != nil {
 		log.Fatalf("Error loading .env file: %v", err)
 		return nil, err
 	}
 
+	switch backend := os.Getenv("STORE_BACKEND"); backend {
+	case "", "mongo":
+		client, err := connectMongo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s := store.NewMongoStore(client)
+		if err := s.EnsureIndexes(ctx); err != nil {
+			_ = s.Close(ctx)
+			return nil, err
+		}
+		return s, nil
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "dynamicrecipes.db"
+		}
+		return store.NewSQLiteStore(ctx, path)
+	case "memory":
+		return store.NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown STORE_BACKEND %q", backend)
+	}
+}
+
+func connectMongo(ctx context.Context) (*mongo.Client, error) {
 	// Get the value of the environment variable
 	connectionString := os.Getenv("MONGODB_URI_STRING")
 
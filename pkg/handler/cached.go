@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"context"
+	"dynamicrecipes/pkg/cache"
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/repository"
+	"dynamicrecipes/pkg/store"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// getAllIngredients returns every ingredient in listing order. The order is
+// cached as a list of IDs and the ingredients themselves per entity, so an
+// update only invalidates the ingredient it touches.
+func getAllIngredients(s store.Store) ([]model.Ingredient, error) {
+	ids, err := cache.Lists.GetOrLoad("allIngredients", func() ([]string, error) {
+		gen := cache.IngredientDeps.Generation()
+		results, err := s.Ingredients().List(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, 0, len(results))
+		for _, ingredient := range results {
+			cache.SetIngredient(gen, ingredient)
+			ids = append(ids, ingredient.ObjectID.Hex())
+		}
+		return ids, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return getIngredients(s, ids)
+}
+
+// getIngredients returns the ingredients with the given IDs in order, loading
+// the ones missing from the cache with a single query. IDs that no longer
+// exist are skipped.
+func getIngredients(s store.Store, ids []string) ([]model.Ingredient, error) {
+	found := make(map[string]model.Ingredient, len(ids))
+	var missing []string
+	for _, id := range ids {
+		if ingredient, ok := cache.Ingredients.Get(id); ok {
+			found[id] = ingredient
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		gen := cache.IngredientDeps.Generation()
+		loaded, err := repository.NewIngredientRepository(s).FindByIDs(context.TODO(), missing)
+		if err != nil {
+			return nil, err
+		}
+		for id, ingredient := range loaded {
+			cache.SetIngredient(gen, ingredient)
+			found[id] = ingredient
+		}
+	}
+
+	ingredients := make([]model.Ingredient, 0, len(ids))
+	for _, id := range ids {
+		if ingredient, ok := found[id]; ok {
+			ingredients = append(ingredients, ingredient)
+		}
+	}
+	return ingredients, nil
+}
+
+// getAllRecipes returns every recipe with its ingredients resolved, cached the
+// same way as getAllIngredients. Cached recipes are dropped when one of the
+// ingredients they embed changes.
+func getAllRecipes(s store.Store) ([]model.Recipe, error) {
+	ids, err := cache.Lists.GetOrLoad("allRecipes", func() ([]string, error) {
+		gen := cache.RecipeDeps.Generation()
+		results, err := repository.NewRecipeRepository(s).List(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		recipes, err := resolveRecipes(context.TODO(), repository.NewIngredientRepository(s), results)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, 0, len(recipes))
+		for _, recipe := range recipes {
+			cache.SetRecipe(gen, recipe)
+			ids = append(ids, recipe.ObjectID.Hex())
+		}
+		return ids, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return getRecipes(s, ids)
+}
+
+// getRecipes returns the resolved recipes with the given IDs in order, loading
+// and resolving the ones missing from the cache in one batch. IDs that no
+// longer exist are skipped.
+func getRecipes(s store.Store, ids []string) ([]model.Recipe, error) {
+	found := make(map[string]model.Recipe, len(ids))
+	var missing []string
+	for _, id := range ids {
+		if recipe, ok := cache.Recipes.Get(id); ok {
+			found[id] = recipe
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		gen := cache.RecipeDeps.Generation()
+		loaded, err := repository.NewRecipeRepository(s).FindByIDs(context.TODO(), missing)
+		if err != nil {
+			return nil, err
+		}
+		recipeItems := make([]model.RecipeReturnType, 0, len(loaded))
+		for _, recipeItem := range loaded {
+			recipeItems = append(recipeItems, recipeItem)
+		}
+		resolved, err := resolveRecipes(context.TODO(), repository.NewIngredientRepository(s), recipeItems)
+		if err != nil {
+			return nil, err
+		}
+		for _, recipe := range resolved {
+			cache.SetRecipe(gen, recipe)
+			found[recipe.ObjectID.Hex()] = recipe
+		}
+	}
+
+	recipes := make([]model.Recipe, 0, len(ids))
+	for _, id := range ids {
+		if recipe, ok := found[id]; ok {
+			recipes = append(recipes, recipe)
+		}
+	}
+	return recipes, nil
+}
+
+// getRecipe loads a single recipe with its ingredients resolved, from the
+// cache when possible. Errors are returned as HTTP errors ready to be sent to
+// the client.
+func getRecipe(s store.Store, id string) (model.Recipe, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return model.Recipe{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid recipe ID")
+	}
+	if recipe, ok := cache.Recipes.Get(objID.Hex()); ok {
+		return recipe, nil
+	}
+
+	gen := cache.RecipeDeps.Generation()
+	recipeItem, err := repository.NewRecipeRepository(s).FindByID(context.TODO(), objID.Hex())
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return model.Recipe{}, echo.NewHTTPError(http.StatusNotFound, "No recipe found with the given ID")
+		}
+		return model.Recipe{}, echo.NewHTTPError(http.StatusInternalServerError, "unable to fetch recipe")
+	}
+
+	recipe, err := resolveRecipe(context.TODO(), repository.NewIngredientRepository(s), *recipeItem)
+	if err != nil {
+		return model.Recipe{}, echo.NewHTTPError(http.StatusInternalServerError, "unable to resolve recipe ingredients")
+	}
+	cache.SetRecipe(gen, recipe)
+	return recipe, nil
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"dynamicrecipes/pkg/model"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// writeTracker remembers when collections and single entities were last
+// written by this process, to serve Last-Modified. Anything not written since
+// startup reports the startup time, since writes made before it are unknown.
+type writeTracker struct {
+	mu      sync.RWMutex
+	started time.Time
+	times   map[string]time.Time
+}
+
+var writes = &writeTracker{started: time.Now(), times: make(map[string]time.Time)}
+
+// Tracker keys for whole collections; single entities use ingredientKey and
+// recipeKey.
+const (
+	ingredientsKey = "ingredients"
+	recipesKey     = "recipes"
+)
+
+func ingredientKey(id string) string { return "ingredient:" + id }
+
+func recipeKey(id string) string { return "recipe:" + id }
+
+// touch records a write to every given key.
+func (w *writeTracker) touch(keys ...string) {
+	now := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, key := range keys {
+		w.times[key] = now
+	}
+}
+
+// since returns the latest write to any of the given keys.
+func (w *writeTracker) since(keys ...string) time.Time {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	latest := w.started
+	for _, key := range keys {
+		if t, ok := w.times[key]; ok && t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// etagOf returns a strong ETag for an encoded response body.
+func etagOf(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// etag returns the ETag a GET of v would be served with.
+func etag(v any) (string, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return etagOf(body), nil
+}
+
+// etagMatches reports whether header, an If-Match or If-None-Match value,
+// lists tag or is "*". Weak validators are compared by their opaque tag.
+func etagMatches(header, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// conditionalJSON sends v as JSON with ETag and Last-Modified headers, or an
+// empty 304 when the request's If-None-Match (or, failing that,
+// If-Modified-Since) shows the client already has it.
+func conditionalJSON(c echo.Context, v any, modified time.Time) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tag := etagOf(body)
+	modified = modified.UTC().Truncate(time.Second)
+
+	header := c.Response().Header()
+	header.Set("ETag", tag)
+	header.Set("Last-Modified", modified.Format(http.TimeFormat))
+
+	req := c.Request()
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		if etagMatches(inm, tag) {
+			return c.NoContent(http.StatusNotModified)
+		}
+	} else if ims, err := http.ParseTime(req.Header.Get("If-Modified-Since")); err == nil && !modified.After(ims) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSONBlob(http.StatusOK, body)
+}
+
+// checkIfMatch enforces If-Match on a write to the resource whose current
+// representation is current: it fails with 428 when the header is missing and
+// with 412 when it does not match, so that concurrent edits from two clients
+// cannot silently overwrite each other. Writes that slip in between this check
+// and the write are caught by basing the versioned update or delete on the
+// version of current, and answered with 409 like any stale write.
+func checkIfMatch(c echo.Context, current any) error {
+	header := c.Request().Header.Get("If-Match")
+	if header == "" {
+		return echo.NewHTTPError(http.StatusPreconditionRequired, "If-Match header is required")
+	}
+	tag, err := etag(current)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "unable to compute ETag")
+	}
+	if !etagMatches(header, tag) {
+		return echo.NewHTTPError(http.StatusPreconditionFailed, map[string]interface{}{
+			"message": "Resource was modified; fetch it again and retry",
+			"etag":    tag,
+		})
+	}
+	return nil
+}
+
+// recipeModified returns the last write to a recipe or to any ingredient it
+// embeds.
+func recipeModified(recipe model.Recipe) time.Time {
+	keys := []string{recipeKey(recipe.ObjectID.Hex())}
+	for _, line := range recipe.Ingredients {
+		keys = append(keys, ingredientKey(line.ObjectID.Hex()))
+	}
+	return writes.since(keys...)
+}
@@ -0,0 +1,313 @@
+package handler
+
+import (
+	"context"
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/store"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// racingStore makes a write land between the If-Match check of a delete and
+// the delete itself, as a concurrent PUT from another client would.
+type racingStore struct {
+	store.Store
+}
+
+func (s racingStore) Ingredients() store.IngredientStore {
+	return racingIngredientStore{s.Store.Ingredients()}
+}
+
+func (s racingStore) Recipes() store.RecipeStore {
+	return racingRecipeStore{s.Store.Recipes()}
+}
+
+type racingIngredientStore struct {
+	store.IngredientStore
+}
+
+func (s racingIngredientStore) DeleteByID(ctx context.Context, id primitive.ObjectID, version int64) error {
+	calories := 5
+	if _, err := s.UpdateByID(ctx, id, version, model.IngredientUpdate{Calories: &calories}); err != nil {
+		return err
+	}
+	return s.IngredientStore.DeleteByID(ctx, id, version)
+}
+
+type racingRecipeStore struct {
+	store.RecipeStore
+}
+
+func (s racingRecipeStore) DeleteByID(ctx context.Context, id primitive.ObjectID, version int64) error {
+	servings := 8
+	if _, err := s.UpdateByID(ctx, id, version, model.RecipeUpdate{Servings: &servings}); err != nil {
+		return err
+	}
+	return s.RecipeStore.DeleteByID(ctx, id, version)
+}
+
+func TestDeleteRecipePreconditions(t *testing.T) {
+	tests := []struct {
+		name string
+		// ifMatch returns the If-Match header to send, given the ETag of the
+		// recipe as fetched before the delete.
+		ifMatch func(t *testing.T, e *echo.Echo, id, tag string) string
+		racing  bool
+		status  int
+	}{
+		{name: "missing If-Match", ifMatch: func(*testing.T, *echo.Echo, string, string) string { return "" }, status: http.StatusPreconditionRequired},
+		{name: "current ETag", ifMatch: func(_ *testing.T, _ *echo.Echo, _, tag string) string { return tag }, status: http.StatusOK},
+		{name: "wildcard", ifMatch: func(*testing.T, *echo.Echo, string, string) string { return "*" }, status: http.StatusOK},
+		{
+			name: "ETag of an older version",
+			ifMatch: func(t *testing.T, e *echo.Echo, id, tag string) string {
+				rec := serve(e, http.MethodPut, "/recipes/"+id, map[string]any{"Servings": 3}, "If-Match", tag)
+				expectStatus(t, rec, http.StatusOK)
+				return tag
+			},
+			status: http.StatusPreconditionFailed,
+		},
+		{name: "update between check and delete", ifMatch: func(_ *testing.T, _ *echo.Echo, _, tag string) string { return tag }, racing: true, status: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := store.NewMemoryStore()
+			_, id := seedPancakes(t, memory)
+			var s store.Store = memory
+			if tt.racing {
+				s = racingStore{memory}
+			}
+			e := newTestServer(t, s)
+
+			rec := serve(e, http.MethodGet, "/recipes/"+id.Hex(), nil)
+			expectStatus(t, rec, http.StatusOK)
+			var headers []string
+			if ifMatch := tt.ifMatch(t, e, id.Hex(), rec.Header().Get("ETag")); ifMatch != "" {
+				headers = []string{"If-Match", ifMatch}
+			}
+
+			rec = serve(e, http.MethodDelete, "/recipes/"+id.Hex(), nil, headers...)
+			expectStatus(t, rec, tt.status)
+			if tt.status == http.StatusOK && !strings.Contains(rec.Body.String(), "Recipe successfully deleted") {
+				t.Errorf("body %s does not report the recipe as deleted", rec.Body.String())
+			}
+
+			_, err := memory.Recipes().FindByID(context.Background(), id)
+			if deleted := errors.Is(err, store.ErrNotFound); deleted != (tt.status == http.StatusOK) {
+				t.Errorf("recipe deleted: %v, want %v", deleted, tt.status == http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestDeleteIngredientPreconditions(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch func(t *testing.T, e *echo.Echo, id, tag string) string
+		racing  bool
+		status  int
+	}{
+		{name: "missing If-Match", ifMatch: func(*testing.T, *echo.Echo, string, string) string { return "" }, status: http.StatusPreconditionRequired},
+		{name: "current ETag", ifMatch: func(_ *testing.T, _ *echo.Echo, _, tag string) string { return tag }, status: http.StatusOK},
+		{
+			name: "ETag of an older version",
+			ifMatch: func(t *testing.T, e *echo.Echo, id, tag string) string {
+				rec := serve(e, http.MethodPut, "/ingredients/"+id, map[string]any{"Calories": 3}, "If-Match", tag)
+				expectStatus(t, rec, http.StatusOK)
+				return tag
+			},
+			status: http.StatusPreconditionFailed,
+		},
+		{name: "update between check and delete", ifMatch: func(_ *testing.T, _ *echo.Echo, _, tag string) string { return tag }, racing: true, status: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := store.NewMemoryStore()
+			id, _ := seedPancakes(t, memory)
+			flour, err := memory.Ingredients().FindByID(context.Background(), id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var s store.Store = memory
+			if tt.racing {
+				s = racingStore{memory}
+			}
+			e := newTestServer(t, s)
+
+			rec := serve(e, http.MethodGet, "/ingredient?id="+id.Hex(), nil)
+			expectStatus(t, rec, http.StatusOK)
+			var headers []string
+			if ifMatch := tt.ifMatch(t, e, id.Hex(), rec.Header().Get("ETag")); ifMatch != "" {
+				headers = []string{"If-Match", ifMatch}
+			}
+
+			rec = serve(e, http.MethodDelete, "/ingredients/"+url.PathEscape(flour.Name)+"?mode=cascade", nil, headers...)
+			expectStatus(t, rec, tt.status)
+
+			_, err = memory.Ingredients().FindByID(context.Background(), id)
+			if deleted := errors.Is(err, store.ErrNotFound); deleted != (tt.status == http.StatusOK) {
+				t.Errorf("ingredient deleted: %v, want %v", deleted, tt.status == http.StatusOK)
+			}
+			recipes, err := memory.Recipes().List(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cascaded := len(recipes) == 0; cascaded != (tt.status == http.StatusOK) {
+				t.Errorf("recipes cascaded: %v, want %v", cascaded, tt.status == http.StatusOK)
+			}
+		})
+	}
+}
+
+// barrierStore holds back ingredient and recipe updates until n of them have
+// arrived, so that concurrent requests all pass their If-Match check before
+// any of them writes.
+type barrierStore struct {
+	store.Store
+	arrive func()
+}
+
+func newBarrierStore(s store.Store, n int) barrierStore {
+	var mu sync.Mutex
+	release := make(chan struct{})
+	return barrierStore{Store: s, arrive: func() {
+		mu.Lock()
+		n--
+		if n == 0 {
+			close(release)
+		}
+		mu.Unlock()
+		<-release
+	}}
+}
+
+func (s barrierStore) Ingredients() store.IngredientStore {
+	return barrierIngredientStore{s.Store.Ingredients(), s.arrive}
+}
+
+func (s barrierStore) Recipes() store.RecipeStore {
+	return barrierRecipeStore{s.Store.Recipes(), s.arrive}
+}
+
+type barrierIngredientStore struct {
+	store.IngredientStore
+	arrive func()
+}
+
+func (s barrierIngredientStore) UpdateByID(ctx context.Context, id primitive.ObjectID, version int64, update model.IngredientUpdate) (*model.Ingredient, error) {
+	s.arrive()
+	return s.IngredientStore.UpdateByID(ctx, id, version, update)
+}
+
+type barrierRecipeStore struct {
+	store.RecipeStore
+	arrive func()
+}
+
+func (s barrierRecipeStore) UpdateByID(ctx context.Context, id primitive.ObjectID, version int64, update model.RecipeUpdate) (*model.RecipeReturnType, error) {
+	s.arrive()
+	return s.RecipeStore.UpdateByID(ctx, id, version, update)
+}
+
+func TestUpdateVersionConflict(t *testing.T) {
+	resources := []struct {
+		name string
+		// get and put return the paths to fetch and update the resource.
+		get, put func(flour, pancakes primitive.ObjectID) string
+		// body returns an update, based on version unless it is 0.
+		body func(i int, version int64) map[string]any
+		// otherVersionKey spells the version field in the other case.
+		otherVersionKey string
+	}{
+		{
+			name: "ingredient",
+			get:  func(flour, _ primitive.ObjectID) string { return "/ingredient?id=" + flour.Hex() },
+			put:  func(flour, _ primitive.ObjectID) string { return "/ingredients/" + flour.Hex() },
+			body: func(i int, version int64) map[string]any {
+				body := map[string]any{"calories": 5 + i}
+				if version != 0 {
+					body["version"] = version
+				}
+				return body
+			},
+			otherVersionKey: "Version",
+		},
+		{
+			name: "recipe",
+			get:  func(_, pancakes primitive.ObjectID) string { return "/recipes/" + pancakes.Hex() },
+			put:  func(_, pancakes primitive.ObjectID) string { return "/recipes/" + pancakes.Hex() },
+			body: func(i int, version int64) map[string]any {
+				body := map[string]any{"Servings": 3 + i}
+				if version != 0 {
+					body["Version"] = version
+				}
+				return body
+			},
+			otherVersionKey: "version",
+		},
+	}
+
+	for _, resource := range resources {
+		t.Run(resource.name+"/version in body", func(t *testing.T) {
+			s := store.NewMemoryStore()
+			flour, pancakes := seedPancakes(t, s)
+			e := newTestServer(t, s)
+
+			rec := serve(e, http.MethodPut, resource.put(flour, pancakes), resource.body(0, 1), "If-Match", "*")
+			expectStatus(t, rec, http.StatusOK)
+			rec = serve(e, http.MethodPut, resource.put(flour, pancakes), resource.body(1, 1), "If-Match", "*")
+			expectStatus(t, rec, http.StatusConflict)
+		})
+
+		t.Run(resource.name+"/version in body differing from If-Match", func(t *testing.T) {
+			s := store.NewMemoryStore()
+			flour, pancakes := seedPancakes(t, s)
+			e := newTestServer(t, s)
+
+			rec := serve(e, http.MethodGet, resource.get(flour, pancakes), nil)
+			expectStatus(t, rec, http.StatusOK)
+			tag := rec.Header().Get("ETag")
+
+			rec = serve(e, http.MethodPut, resource.put(flour, pancakes), resource.body(0, 2), "If-Match", tag)
+			expectStatus(t, rec, http.StatusConflict)
+			body := resource.body(0, 0)
+			body[resource.otherVersionKey] = 1
+			rec = serve(e, http.MethodPut, resource.put(flour, pancakes), body, "If-Match", tag)
+			expectStatus(t, rec, http.StatusOK)
+		})
+
+		t.Run(resource.name+"/version from If-Match", func(t *testing.T) {
+			memory := store.NewMemoryStore()
+			flour, pancakes := seedPancakes(t, memory)
+			e := newTestServer(t, newBarrierStore(memory, 2))
+
+			rec := serve(e, http.MethodGet, resource.get(flour, pancakes), nil)
+			expectStatus(t, rec, http.StatusOK)
+			tag := rec.Header().Get("ETag")
+
+			// Both updates are based on the same ETag and pass the If-Match
+			// check; the versioned update lets only the first one through.
+			statuses := make(chan int, 2)
+			for i := 0; i < 2; i++ {
+				go func(i int) {
+					statuses <- serve(e, http.MethodPut, resource.put(flour, pancakes), resource.body(i, 0), "If-Match", tag).Code
+				}(i)
+			}
+			got := []int{<-statuses, <-statuses}
+			slices.Sort(got)
+			if want := []int{http.StatusOK, http.StatusConflict}; !slices.Equal(got, want) {
+				t.Errorf("statuses %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -3,92 +3,213 @@ package handler
 import (
 	"context"
 	"dynamicrecipes/pkg/cache"
+	"dynamicrecipes/pkg/mealplan"
 	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/nutrition"
 	"dynamicrecipes/pkg/repository"
+	"dynamicrecipes/pkg/search"
+	"dynamicrecipes/pkg/shopping"
+	"dynamicrecipes/pkg/store"
+	"dynamicrecipes/pkg/units"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	_ "net/http/pprof"
 	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func getAllRecipes(client *mongo.Client) (*[]model.Recipe, error) {
-	if cachedRecipes, ok := cache.LoadRecipesCache("allRecipes"); ok {
-		return cachedRecipes, nil
+// resolveRecipes replaces the ingredient references of stored recipes with the
+// ingredients themselves. All referenced ingredients are fetched with a single
+// batched query, regardless of how many recipes are resolved.
+func resolveRecipes(ctx context.Context, ingredientRepo *repository.IngredientRepository, recipeItems []model.RecipeReturnType) ([]model.Recipe, error) {
+	var ingredientIDs []string
+	seen := make(map[string]bool)
+	for _, recipeItem := range recipeItems {
+		for _, id := range recipeItem.ID {
+			if !seen[id.ObjectID] {
+				seen[id.ObjectID] = true
+				ingredientIDs = append(ingredientIDs, id.ObjectID)
+			}
+		}
 	}
-	collection := client.Database("Recipe_Service").Collection("recipes")
-	filter := bson.D{{}}
 
-	cur, err := collection.Find(context.TODO(), filter)
+	ingredients, err := ingredientRepo.FindByIDs(ctx, ingredientIDs)
 	if err != nil {
 		return nil, err
 	}
-	defer cur.Close(context.TODO())
 
-	var results model.RecipeResult
-	if err = cur.All(context.TODO(), &results); err != nil {
-		return nil, err
+	recipes := make([]model.Recipe, len(recipeItems))
+	for i, recipeItem := range recipeItems {
+		var ingredientsResponse []model.RecipeIngredient
+		for _, id := range recipeItem.ID {
+			ingredient, ok := ingredients[id.ObjectID]
+			if !ok {
+				return nil, fmt.Errorf("failed to find ingredient %s: %w", id.ObjectID, store.ErrNotFound)
+			}
+			ingredientsResponse = append(ingredientsResponse, model.RecipeIngredient{
+				Ingredient: ingredient,
+				Quantity:   id.Quantity,
+				Unit:       id.Unit,
+			})
+		}
+		details := recipeItem.RecipeDetails
+		if details.Instructions == nil {
+			details.Instructions = []string{}
+		}
+		recipes[i] = model.Recipe{
+			ObjectID:      recipeItem.ObjectID,
+			Name:          recipeItem.Name,
+			Servings:      recipeItem.Servings,
+			Ingredients:   ingredientsResponse,
+			RecipeDetails: details,
+			Nutrition:     nutrition.Compute(ingredientsResponse, recipeItem.Servings),
+			Version:       recipeItem.Version,
+		}
 	}
+	return recipes, nil
+}
 
-	// Prepare a channel to collect errors that might occur in goroutines.
-	errChan := make(chan error, 1)
-	// Prepare a wait group to synchronize all goroutines.
-	var wg sync.WaitGroup
+// resolveRecipe resolves the ingredients of a single stored recipe.
+func resolveRecipe(ctx context.Context, ingredientRepo *repository.IngredientRepository, recipeItem model.RecipeReturnType) (model.Recipe, error) {
+	recipes, err := resolveRecipes(ctx, ingredientRepo, []model.RecipeReturnType{recipeItem})
+	if err != nil {
+		return model.Recipe{}, err
+	}
+	return recipes[0], nil
+}
 
-	finalReturnValue := make([]model.Recipe, len(results))
-	ingredientRepo := repository.NewIngredientRepository(client)
+// validateRecipeIngredients checks that every ingredient line references a valid
+// ObjectID with a non-negative quantity and a known unit, and normalizes the ID
+// and unit to their canonical forms.
+func validateRecipeIngredients(ingredients []model.IngredientIDType) error {
+	for i, ingredient := range ingredients {
+		oid, err := primitive.ObjectIDFromHex(ingredient.ObjectID)
+		if err != nil {
+			return fmt.Errorf("invalid ObjectID %q in Ingredients", ingredient.ObjectID)
+		}
+		if ingredient.Quantity < 0 {
+			return fmt.Errorf("negative Quantity for ingredient %s", ingredient.ObjectID)
+		}
+		ingredients[i].ObjectID = oid.Hex()
 
-	for i, recipeItem := range results {
-		wg.Add(1) // Increment the WaitGroup counter.
-		go func(i int, recipeItem model.RecipeReturnType) {
-			defer wg.Done() // Decrement the counter when the goroutine completes.
+		// An empty unit is allowed for lines that were stored before units existed.
+		if strings.TrimSpace(ingredient.Unit) == "" {
+			ingredients[i].Unit = ""
+			continue
+		}
+		unit, ok := units.Lookup(ingredient.Unit)
+		if !ok {
+			return fmt.Errorf("unknown Unit %q for ingredient %s", ingredient.Unit, ingredient.ObjectID)
+		}
+		ingredients[i].Unit = unit.Symbol
+	}
+	return nil
+}
 
-			var ingredientsResponse []model.Ingredient
-			for _, id := range recipeItem.ID {
-				ingredient, err := ingredientRepo.FindByID(context.TODO(), id.ObjectID)
-				if err != nil {
-					errChan <- err // Send any error that occurs to the error channel.
-					return
-				}
-				ingredientsResponse = append(ingredientsResponse, *ingredient)
-			}
+// convertRecipe expresses every ingredient line of a recipe in the given unit
+// system, moving it into dimension first when one is given. Lines without a
+// unit, or that cannot be converted for lack of density data, are left as is.
+func convertRecipe(recipe model.Recipe, system units.System, dimension units.Dimension) model.Recipe {
+	converted := recipe
+	converted.Ingredients = make([]model.RecipeIngredient, len(recipe.Ingredients))
+	for i, line := range recipe.Ingredients {
+		converted.Ingredients[i] = line
+		if line.Unit == "" {
+			continue
+		}
+		quantity, unit, err := units.ToSystem(line.Quantity, line.Unit, system, dimension, line.Conversion())
+		if err != nil {
+			continue
+		}
+		converted.Ingredients[i].Quantity = units.Round(quantity, unit)
+		converted.Ingredients[i].Unit = unit.Symbol
+	}
+	return converted
+}
 
-			finalReturnValue[i] = model.Recipe{Name: recipeItem.Name, Ingredients: ingredientsResponse}
-		}(i, recipeItem)
+// scaleRecipe rescales every ingredient line of a recipe from its own serving
+// count to servings, rounds the amounts to a sensible precision for their unit
+// (moving to a larger or smaller unit of the same system when that reads
+// better) and recomputes the nutrition. Recipes without a serving count are
+// treated as a single serving.
+func scaleRecipe(recipe model.Recipe, servings int) model.Recipe {
+	base := recipe.Servings
+	if base <= 0 {
+		base = 1
 	}
+	factor := float64(servings) / float64(base)
 
-	// Wait for all goroutines to finish.
-	wg.Wait()
-	close(errChan) // Close the error channel.
+	scaled := recipe
+	scaled.Servings = servings
+	scaled.Ingredients = make([]model.RecipeIngredient, len(recipe.Ingredients))
+	for i, line := range recipe.Ingredients {
+		scaled.Ingredients[i] = line
+		scaled.Ingredients[i].Quantity = line.Quantity * factor
 
-	// Check if any errors were reported by the goroutines.
-	for err := range errChan {
-		if err != nil {
-			return nil, err
+		unit, ok := units.Lookup(line.Unit)
+		if !ok {
+			continue
+		}
+		if unit.System != "" {
+			best := units.Best(line.Quantity*factor*unit.Factor, unit.Dimension, unit.System)
+			scaled.Ingredients[i].Quantity = line.Quantity * factor * unit.Factor / best.Factor
+			unit = best
 		}
+		scaled.Ingredients[i].Quantity = units.Round(scaled.Ingredients[i].Quantity, unit)
+		scaled.Ingredients[i].Unit = unit.Symbol
+	}
+	scaled.Nutrition = nutrition.Compute(scaled.Ingredients, servings)
+	return scaled
+}
+
+// recipeSummary identifies a recipe in error and status responses.
+type recipeSummary struct {
+	ObjectID primitive.ObjectID
+	Name     string
+}
+
+func summarizeRecipes(recipes []model.RecipeReturnType) []recipeSummary {
+	summaries := make([]recipeSummary, 0, len(recipes))
+	for _, recipe := range recipes {
+		summaries = append(summaries, recipeSummary{ObjectID: recipe.ObjectID, Name: recipe.Name})
 	}
-	cache.StoreRecipesInCache("allRecipes", &finalReturnValue)
+	return summaries
+}
 
-	return &finalReturnValue, nil
+// unknownIngredientsHTTPError turns a *repository.UnknownIngredientsError into a
+// 422 response listing the unknown ingredient IDs per recipe, or returns nil
+// for any other error.
+func unknownIngredientsHTTPError(err error) *echo.HTTPError {
+	var unknown *repository.UnknownIngredientsError
+	if !errors.As(err, &unknown) {
+		return nil
+	}
+	return echo.NewHTTPError(http.StatusUnprocessableEntity, map[string]interface{}{
+		"message": "Recipes reference ingredients that do not exist",
+		"recipes": unknown.Recipes,
+	})
 }
 
-// TODO: Implement HTTP handlers
-func InitRoutes(e *echo.Echo, client *mongo.Client) {
+// InitRoutes registers the middleware and routes of the API on e, serving from
+// s. It builds the search index first and fails if that cannot be loaded.
+func InitRoutes(e *echo.Echo, s store.Store) error {
 	corsUrls := os.Getenv("LOCAL_CORS_URLS")
 	allowOrigins := strings.Split(corsUrls, ",")
 	e.Use(middleware.Logger())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: allowOrigins, // Be cautious with *, specify origins if possible
-		AllowMethods: []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete},
+		AllowMethods: []string{http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete},
+		// Let the frontend read the validators it sends back in conditional requests.
+		ExposeHeaders: []string{"ETag", "Last-Modified", nextCursorHeader},
 	}))
 
 	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
@@ -98,29 +219,40 @@ func InitRoutes(e *echo.Echo, client *mongo.Client) {
 		},
 	}))
 
-	e.GET("/ingredients", func(c echo.Context) error {
-		if cachedIngredients, ok := cache.LoadIngredientsCache("allIngredients"); ok {
-			return c.JSON(http.StatusOK, cachedIngredients)
-		}
-		collection := client.Database("Recipe_Service").Collection("Ingredients")
-		filter := bson.D{{}}
+	// Build the search index before serving, while nothing can write.
+	if err := loadSearchIndex(context.TODO(), s); err != nil {
+		return fmt.Errorf("failed to build search index: %w", err)
+	}
+	// Fill in the calories of recipes stored before they were kept.
+	if err := repository.NewRecipeRepository(s).RefreshCalories(context.TODO()); err != nil {
+		return fmt.Errorf("failed to refresh recipe calories: %w", err)
+	}
 
-		cur, err := collection.Find(context.TODO(), filter)
+	e.GET("/ingredients", func(c echo.Context) error {
+		params, paged, err := listParams(c)
 		if err != nil {
-			log.Fatal(err) // Consider how to handle errors more gracefully
+			return err
+		}
+		if paged {
+			page, next, err := repository.NewIngredientRepository(s).Page(context.TODO(), params)
+			if err != nil {
+				return listError(err, "unable to fetch ingredients")
+			}
+			if next != "" {
+				c.Response().Header().Set(nextCursorHeader, next)
+			}
+			return conditionalJSON(c, page, writes.since(ingredientsKey))
 		}
-		defer cur.Close(context.TODO())
 
-		var results model.IngredientsResult
-		if err = cur.All(context.TODO(), &results); err != nil {
-			log.Fatal(err)
+		results, err := getAllIngredients(s)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "unable to fetch ingredients")
 		}
-		cache.StoreIngredientsInCache("allIngredients", &results)
 
 		// for _, ingredient := range results {
 		// 	fmt.Printf("Name: %s, Calories: %d\n", ingredient.Name, ingredient.Calories)
 		// }
-		return c.JSON(http.StatusOK, results)
+		return conditionalJSON(c, results, writes.since(ingredientsKey))
 	})
 
 	e.GET("/ingredient", func(c echo.Context) error {
@@ -130,31 +262,118 @@ func InitRoutes(e *echo.Echo, client *mongo.Client) {
 			return echo.NewHTTPError(http.StatusBadRequest, "No params provided")
 		}
 
-		ingredientsRepo := repository.NewIngredientRepository(client)
-
-		ingredient, err := ingredientsRepo.FindByID(context.TODO(), idStr)
+		objID, err := primitive.ObjectIDFromHex(idStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid ingredient ID")
+		}
 
+		ingredients, err := getIngredients(s, []string{objID.Hex()})
 		if err != nil {
-			echo.NewHTTPError(http.StatusInternalServerError, "No Ingredient with specified id ")
+			return echo.NewHTTPError(http.StatusInternalServerError, "unable to fetch ingredient")
+		}
+		if len(ingredients) == 0 {
+			return echo.NewHTTPError(http.StatusNotFound, "No Ingredient with specified id ")
 		}
 
-		return c.JSON(http.StatusOK, ingredient)
+		return conditionalJSON(c, ingredients[0], writes.since(ingredientKey(objID.Hex())))
 
 	})
 
 	e.GET("/recipes", func(c echo.Context) error {
-		result, err := getAllRecipes(client)
+		params, paged, err := listParams(c)
+		if err != nil {
+			return err
+		}
+		if paged {
+			page, next, err := repository.NewRecipeRepository(s).Page(context.TODO(), params)
+			if err != nil {
+				return listError(err, "unable to fetch recipes")
+			}
+			recipes, err := resolveRecipes(context.TODO(), repository.NewIngredientRepository(s), page)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "unable to fetch recipes")
+			}
+			if next != "" {
+				c.Response().Header().Set(nextCursorHeader, next)
+			}
+			return conditionalJSON(c, recipes, writes.since(recipesKey, ingredientsKey))
+		}
+
+		result, err := getAllRecipes(s)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "unable to fetch recipes")
 		}
 
-		return c.JSON(200, result)
+		return conditionalJSON(c, result, writes.since(recipesKey, ingredientsKey))
+	})
+
+	e.GET("/recipes/match", func(c echo.Context) error {
+		// The ingredients at hand, as repeated or comma-separated have= params.
+		var have []string
+		for _, param := range c.QueryParams()["have"] {
+			for _, id := range strings.Split(param, ",") {
+				if id = strings.TrimSpace(id); id != "" {
+					have = append(have, id)
+				}
+			}
+		}
+		if len(have) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "have must list at least one ingredient ID")
+		}
+
+		limit, err := limitParam(c, 20, 100)
+		if err != nil {
+			return err
+		}
+		opts := repository.MatchOptions{Limit: limit}
+		if param := c.QueryParam("maxMissing"); param != "" {
+			maxMissing, err := strconv.Atoi(param)
+			if err != nil || maxMissing < 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, "maxMissing must be a non-negative integer")
+			}
+			opts.MaxMissing = &maxMissing
+		}
+
+		matches, err := repository.NewRecipeRepository(s).Match(context.TODO(), have, opts)
+		if err != nil {
+			if errors.Is(err, primitive.ErrInvalidHex) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid ingredient ID")
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "unable to match recipes")
+		}
+
+		return conditionalJSON(c, matches, writes.since(recipesKey, ingredientsKey))
+	})
+
+	e.GET("/recipes/:id", func(c echo.Context) error {
+		recipe, err := getRecipe(s, c.Param("id"))
+		if err != nil {
+			return err
+		}
+
+		// Optionally scale the recipe to the requested number of servings.
+		if param := c.QueryParam("servings"); param != "" {
+			servings, err := strconv.Atoi(param)
+			if err != nil || servings <= 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, "servings must be a positive integer")
+			}
+			recipe = scaleRecipe(recipe, servings)
+		}
+
+		return conditionalJSON(c, recipe, recipeModified(recipe))
 	})
 
 	e.POST("/ingredients", func(c echo.Context) error {
 		type Ingredient struct {
-			Name     string `bson:"name"`
-			Calories int    `bson:"calories_per_gram"`
+			Name          string  `bson:"name"`
+			Calories      int     `bson:"calories_per_gram"`
+			Density       float64 `bson:"density_g_per_ml"`
+			GramsPerPiece float64 `bson:"grams_per_piece"`
+			Protein       float64 `bson:"protein_per_gram"`
+			Fat           float64 `bson:"fat_per_gram"`
+			Carbs         float64 `bson:"carbs_per_gram"`
+			Fiber         float64 `bson:"fiber_per_gram"`
+			Category      string  `bson:"category"`
 		}
 		var newIngredients []Ingredient // Assuming Ingredient is your struct type for the collection
 		// fmt.Print(c)
@@ -162,23 +381,44 @@ func InitRoutes(e *echo.Echo, client *mongo.Client) {
 		if err := c.Bind(&newIngredients); err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, "Invalid input")
 		}
-		// Prepare a slice of interface{} to hold the documents for insertion
-		var docs []interface{}
+		// Prepare the documents for insertion
+		docs := make([]model.Ingredient, 0, len(newIngredients))
 		for _, ingredient := range newIngredients {
-			docs = append(docs, ingredient)
+			if ingredient.Density < 0 || ingredient.GramsPerPiece < 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, "Density and GramsPerPiece must not be negative")
+			}
+			if ingredient.Protein < 0 || ingredient.Fat < 0 || ingredient.Carbs < 0 || ingredient.Fiber < 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, "Protein, Fat, Carbs and Fiber must not be negative")
+			}
+			docs = append(docs, model.Ingredient{
+				Name:          ingredient.Name,
+				Calories:      ingredient.Calories,
+				Density:       ingredient.Density,
+				GramsPerPiece: ingredient.GramsPerPiece,
+				Protein:       ingredient.Protein,
+				Fat:           ingredient.Fat,
+				Carbs:         ingredient.Carbs,
+				Fiber:         ingredient.Fiber,
+				Category:      strings.TrimSpace(ingredient.Category),
+			})
 		}
-		// Inserting the documents into the collection
-		collection := client.Database("Recipe_Service").Collection("Ingredients")
-		result, err := collection.InsertMany(context.TODO(), docs)
+		// Inserting the documents into the store
+		insertedIDs, err := s.Ingredients().InsertMany(context.TODO(), docs)
 		if err != nil {
-			// Handle error appropriately
-			fmt.Print((err))
+			c.Logger().Errorf("failed to insert ingredients: %v", err)
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to insert ingredients")
 		}
 
-		cache.InvalidateIngredientsCache("allIngredients")
+		cache.Lists.Delete("allIngredients")
+		touched := []string{ingredientsKey}
+		for i, id := range insertedIDs {
+			touched = append(touched, ingredientKey(id.Hex()))
+			docs[i].ObjectID = id
+			indexIngredient(docs[i])
+		}
+		writes.touch(touched...)
 		// Respond with the result of the insert operation
-		return c.JSON(http.StatusCreated, result.InsertedIDs)
+		return c.JSON(http.StatusCreated, insertedIDs)
 	})
 
 	e.POST("/recipes", func(c echo.Context) error {
@@ -189,31 +429,41 @@ func InitRoutes(e *echo.Echo, client *mongo.Client) {
 			return echo.NewHTTPError(http.StatusBadRequest, "Invalid input")
 		}
 
-		// Prepare a slice of interface{} to hold the documents for insertion
-		var docs []interface{}
+		// Prepare the documents for insertion
+		docs := make([]model.RecipePostType, 0, len(newRecipes))
 		for _, recipe := range newRecipes {
-			// Convert IngredientIDType to primitive.ObjectID
-			for i, ingredient := range recipe.Ingredients {
-				if oid, err := primitive.ObjectIDFromHex(ingredient.ObjectID); err == nil {
-					recipe.Ingredients[i] = model.IngredientIDType{ObjectID: oid.Hex()}
-				} else {
-					// Handle error if conversion fails
-					return echo.NewHTTPError(http.StatusBadRequest, "Invalid ObjectID in Ingredients")
-				}
+			if err := validateRecipeIngredients(recipe.Ingredients); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			if recipe.Servings < 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, "Servings must not be negative")
 			}
+			details, err := recipeDetails(recipe.RecipeDetails)
+			if err != nil {
+				return err
+			}
+			recipe.RecipeDetails = details
 			docs = append(docs, recipe)
 		}
 
-		// Inserting the documents into the collection
-		collection := client.Database("Recipe_Service").Collection("recipes")
-		result, err := collection.InsertMany(context.TODO(), docs)
+		// Inserting the documents into the store
+		insertedIDs, err := repository.NewRecipeRepository(s).Insert(context.TODO(), docs)
 		if err != nil {
+			if httpErr := unknownIngredientsHTTPError(err); httpErr != nil {
+				return httpErr
+			}
 			// Handle error appropriately
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to insert recipes")
 		}
-		cache.InvalidateRecipesCache("allRecipes")
+		cache.Lists.Delete("allRecipes")
+		touched := []string{recipesKey}
+		for i, id := range insertedIDs {
+			touched = append(touched, recipeKey(id.Hex()))
+			searchIndex.Put(search.RecipeDocument(model.RecipeReturnType{ObjectID: id, Name: docs[i].Name, RecipeDetails: docs[i].RecipeDetails}))
+		}
+		writes.touch(touched...)
 		// Respond with the result of the insert operation
-		return c.JSON(http.StatusCreated, result.InsertedIDs)
+		return c.JSON(http.StatusCreated, insertedIDs)
 	})
 	e.DELETE("/ingredients/:name", func(c echo.Context) error {
 		name := c.Param("name")
@@ -223,46 +473,107 @@ func InitRoutes(e *echo.Echo, client *mongo.Client) {
 			return echo.NewHTTPError(http.StatusBadRequest, "Invalid search parameter")
 		}
 
-		ingredientsRepository := repository.NewIngredientRepository(client)
+		// Decide what happens to recipes that still use the ingredient.
+		mode := repository.DeleteMode(c.QueryParam("mode"))
+		switch mode {
+		case repository.DeleteRestrict, repository.DeleteCascade, repository.DeleteDetach:
+		default:
+			return echo.NewHTTPError(http.StatusBadRequest, "mode must be cascade or detach")
+		}
+
+		// Only delete the ingredient the client last saw.
+		current, err := s.Ingredients().FindByName(context.TODO(), decodedParam)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "No ingredient found with the given name")
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "Could not delete ingredient")
+		}
+		if err := checkIfMatch(c, *current); err != nil {
+			return err
+		}
+
+		ingredientsRepository := repository.NewIngredientRepository(s)
 
-		result, err := ingredientsRepository.DeleteByName(context.TODO(), decodedParam)
+		deletedIngredient, affectedRecipes, err := ingredientsRepository.DeleteByName(context.TODO(), decodedParam, current.Version, mode)
 
 		if err != nil {
+			if errors.Is(err, store.ErrVersionConflict) {
+				return echo.NewHTTPError(http.StatusConflict, "Ingredient was modified concurrently; fetch it again and retry")
+			}
+			var inUse *repository.IngredientInUseError
+			if errors.As(err, &inUse) {
+				return echo.NewHTTPError(http.StatusConflict, map[string]interface{}{
+					"message": "Ingredient is still used by recipes; retry with mode=cascade or mode=detach",
+					"name":    decodedParam,
+					"recipes": summarizeRecipes(inUse.Recipes),
+				})
+			}
 			return echo.NewHTTPError(http.StatusInternalServerError, "Could not delete ingredient")
 		}
 
-		if result.DeletedCount == 0 {
+		if deletedIngredient == nil {
 			// No document was found with the provided name
 			return echo.NewHTTPError(http.StatusNotFound, "No ingredient found with the given name")
 		}
 
-		cache.InvalidateIngredientsCache("allIngredients")
+		// Detached recipes embed the ingredient and are dropped along with it.
+		cache.InvalidateIngredient(deletedIngredient.ObjectID.Hex())
+		cache.Lists.Delete("allIngredients")
+		unindexIngredient(deletedIngredient.ObjectID.Hex())
+		if mode == repository.DeleteCascade && len(affectedRecipes) > 0 {
+			cache.Lists.Delete("allRecipes")
+			for _, recipe := range affectedRecipes {
+				searchIndex.Remove(search.KindRecipe, recipe.ObjectID.Hex())
+			}
+		}
+		touched := []string{ingredientsKey, ingredientKey(deletedIngredient.ObjectID.Hex())}
+		if len(affectedRecipes) > 0 {
+			touched = append(touched, recipesKey)
+			for _, recipe := range affectedRecipes {
+				touched = append(touched, recipeKey(recipe.ObjectID.Hex()))
+			}
+		}
+		writes.touch(touched...)
 		return c.JSON(http.StatusOK, map[string]interface{}{
 			"message": "Ingredient successfully deleted",
 			"name":    decodedParam,
+			"mode":    mode,
+			"recipes": summarizeRecipes(affectedRecipes),
 		})
 	})
 	e.DELETE("/recipes/:id", func(c echo.Context) error {
 		id := c.Param("id")
-		objID, err := primitive.ObjectIDFromHex(id)
+
+		// Only delete the recipe the client last saw.
+		current, err := getRecipe(s, id)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Could not convert hex to object ID")
+			return err
+		}
+		if err := checkIfMatch(c, current); err != nil {
+			return err
 		}
 
-		filter := bson.D{{Key: "_id", Value: objID}}
-		collection := client.Database("Recipe_Service").Collection("recipes")
-
-		result, err := collection.DeleteOne(context.TODO(), filter)
+		deletedCount, err := repository.NewRecipeRepository(s).DeleteByID(context.TODO(), id, current.Version)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Could not delete ingredient")
+			if errors.Is(err, store.ErrVersionConflict) {
+				return echo.NewHTTPError(http.StatusConflict, "Recipe was modified concurrently; fetch it again and retry")
+			}
+			if errors.Is(err, primitive.ErrInvalidHex) {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Could not convert hex to object ID")
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "Could not delete recipe")
 		}
-		if result.DeletedCount == 0 {
-			// No document was found with the provided name
-			return echo.NewHTTPError(http.StatusNotFound, "No ingredient found with the given Object Id")
+		if deletedCount == 0 {
+			// No document was found with the provided ID
+			return echo.NewHTTPError(http.StatusNotFound, "No recipe found with the given Object Id")
 		}
-		cache.InvalidateRecipesCache("allRecipes")
+		cache.InvalidateRecipe(current.ObjectID.Hex())
+		cache.Lists.Delete("allRecipes")
+		searchIndex.Remove(search.KindRecipe, current.ObjectID.Hex())
+		writes.touch(recipesKey, recipeKey(current.ObjectID.Hex()))
 		return c.JSON(http.StatusOK, map[string]interface{}{
-			"message": "Ingredient successfully deleted",
+			"message": "Recipe successfully deleted",
 			"id":      id,
 		})
 	})
@@ -273,8 +584,19 @@ func InitRoutes(e *echo.Echo, client *mongo.Client) {
 
 		// Define a struct for the request body. Here, we allow either field to be updated.
 		type updateRequest struct {
-			Name     *string `json:"name,omitempty"`
-			Calories *int    `json:"calories,omitempty"`
+			Name          *string  `json:"name,omitempty"`
+			Calories      *int     `json:"calories,omitempty"`
+			Density       *float64 `json:"density,omitempty"`
+			GramsPerPiece *float64 `json:"gramsPerPiece,omitempty"`
+			Protein       *float64 `json:"protein,omitempty"`
+			Fat           *float64 `json:"fat,omitempty"`
+			Carbs         *float64 `json:"carbs,omitempty"`
+			Fiber         *float64 `json:"fiber,omitempty"`
+			Category      *string  `json:"category,omitempty"`
+			// Version optionally repeats the version the change is based
+			// on. It must be the one matched by If-Match. Field names
+			// match case-insensitively, so "Version" works too.
+			Version *int64 `json:"version,omitempty"`
 		}
 		var updateData updateRequest
 
@@ -284,19 +606,53 @@ func InitRoutes(e *echo.Echo, client *mongo.Client) {
 		}
 
 		// Create an update document based on the provided data.
-		update := bson.M{}
-		if updateData.Name != nil {
-			update["name"] = *updateData.Name
+		for _, value := range []*float64{updateData.Density, updateData.GramsPerPiece, updateData.Protein, updateData.Fat, updateData.Carbs, updateData.Fiber} {
+			if value != nil && *value < 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, "per-gram and density values must not be negative")
+			}
+		}
+		update := model.IngredientUpdate{
+			Name:          updateData.Name,
+			Calories:      updateData.Calories,
+			Density:       updateData.Density,
+			GramsPerPiece: updateData.GramsPerPiece,
+			Protein:       updateData.Protein,
+			Fat:           updateData.Fat,
+			Carbs:         updateData.Carbs,
+			Fiber:         updateData.Fiber,
+			Category:      updateData.Category,
+		}
+		if update.Category != nil {
+			category := strings.TrimSpace(*update.Category)
+			update.Category = &category
+		}
+
+		// Get the repository and perform the update, but only on top of the
+		// version of the ingredient the client last saw.
+		ingredientsRepository := repository.NewIngredientRepository(s)
+		current, err := ingredientsRepository.FindByID(context.TODO(), id)
+		if err != nil {
+			if errors.Is(err, primitive.ErrInvalidHex) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid ingredient ID")
+			}
+			if errors.Is(err, store.ErrNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "No ingredient found with the given ID")
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "Could not update ingredient")
 		}
-		if updateData.Calories != nil {
-			update["calories_per_gram"] = *updateData.Calories
+		if err := checkIfMatch(c, *current); err != nil {
+			return err
 		}
 
-		// Get the repository and perform the update.
-		ingredientsRepository := repository.NewIngredientRepository(client)
-		updatedIngredient, err := ingredientsRepository.UpdateByID(context.TODO(), id, update)
+		if updateData.Version != nil && *updateData.Version != current.Version {
+			return echo.NewHTTPError(http.StatusConflict, "Ingredient was modified concurrently; fetch it again and retry")
+		}
+		updatedIngredient, err := ingredientsRepository.UpdateByID(context.TODO(), id, current.Version, update)
 
 		if err != nil {
+			if errors.Is(err, store.ErrVersionConflict) {
+				return echo.NewHTTPError(http.StatusConflict, "Ingredient was modified concurrently; fetch it again and retry")
+			}
 			return echo.NewHTTPError(http.StatusInternalServerError, "Could not update ingredient")
 		}
 
@@ -305,7 +661,13 @@ func InitRoutes(e *echo.Echo, client *mongo.Client) {
 			return echo.NewHTTPError(http.StatusNotFound, "No ingredient found with the given ID")
 		}
 
-		cache.InvalidateIngredientsCache("allIngredients")
+		cache.InvalidateIngredient(updatedIngredient.ObjectID.Hex())
+		indexIngredient(*updatedIngredient)
+		writes.touch(ingredientsKey, ingredientKey(updatedIngredient.ObjectID.Hex()))
+		// Hand out the new validator so the client can chain edits.
+		if tag, err := etag(*updatedIngredient); err == nil {
+			c.Response().Header().Set("ETag", tag)
+		}
 
 		// Return the updated ingredient and a success message.
 		return c.JSON(http.StatusOK, map[string]interface{}{
@@ -314,8 +676,952 @@ func InitRoutes(e *echo.Echo, client *mongo.Client) {
 		})
 	})
 
-	e.GET("/ws", HandleWebSocketConnection)
+	e.GET("/recipes/:id/convert", func(c echo.Context) error {
+		system, ok := units.ParseSystem(c.QueryParam("system"))
+		if !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, "system must be metric or imperial")
+		}
+		var dimension units.Dimension
+		if param := c.QueryParam("dimension"); param != "" {
+			if dimension, ok = units.ParseDimension(param); !ok {
+				return echo.NewHTTPError(http.StatusBadRequest, "dimension must be mass, volume or count")
+			}
+		}
+
+		recipe, err := getRecipe(s, c.Param("id"))
+		if err != nil {
+			return err
+		}
+
+		return conditionalJSON(c, convertRecipe(recipe, system, dimension), recipeModified(recipe))
+	})
+
+	updateRecipe := func(c echo.Context) error {
+		// Extract the recipe ID from the URL parameter.
+		id := c.Param("id")
+
+		// Define a struct for the request body. Only the provided fields are updated.
+		type updateRequest struct {
+			Name        *string                   `json:"Name,omitempty"`
+			Ingredients *[]model.IngredientIDType `json:"Ingredients,omitempty"`
+			Servings    *int                      `json:"Servings,omitempty"`
+			// Instructions replaces all steps; send [] to remove them.
+			Instructions *[]string `json:"Instructions,omitempty"`
+			PrepMinutes  *int      `json:"PrepMinutes,omitempty"`
+			CookMinutes  *int      `json:"CookMinutes,omitempty"`
+			// Difficulty, SourceURL and Notes are cleared by sending "".
+			Difficulty *string `json:"Difficulty,omitempty"`
+			SourceURL  *string `json:"SourceURL,omitempty"`
+			Notes      *string `json:"Notes,omitempty"`
+			// Version optionally repeats the version the change is based
+			// on. It must be the one matched by If-Match. Field names
+			// match case-insensitively, so "version" works too.
+			Version *int64 `json:"Version,omitempty"`
+		}
+		var updateData updateRequest
+
+		// Bind the request body to the struct.
+		if err := c.Bind(&updateData); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid input")
+		}
+		update := model.RecipeUpdate{
+			Name:         updateData.Name,
+			Ingredients:  updateData.Ingredients,
+			Servings:     updateData.Servings,
+			Instructions: updateData.Instructions,
+			PrepMinutes:  updateData.PrepMinutes,
+			CookMinutes:  updateData.CookMinutes,
+			Difficulty:   updateData.Difficulty,
+			SourceURL:    updateData.SourceURL,
+			Notes:        updateData.Notes,
+		}
+		if update == (model.RecipeUpdate{}) {
+			return echo.NewHTTPError(http.StatusBadRequest, "No fields to update")
+		}
+		if updateData.Ingredients != nil {
+			if err := validateRecipeIngredients(*updateData.Ingredients); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+		}
+		if updateData.Servings != nil && *updateData.Servings < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Servings must not be negative")
+		}
+		if err := recipeDetailsUpdate(&update); err != nil {
+			return err
+		}
+
+		// Only update on top of the version of the recipe the client last saw.
+		current, err := getRecipe(s, id)
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(c, current); err != nil {
+			return err
+		}
+
+		if updateData.Version != nil && *updateData.Version != current.Version {
+			return echo.NewHTTPError(http.StatusConflict, "Recipe was modified concurrently; fetch it again and retry")
+		}
+		updatedRecipe, err := repository.NewRecipeRepository(s).UpdateByID(context.TODO(), id, current.Version, update)
+		if err != nil {
+			if httpErr := unknownIngredientsHTTPError(err); httpErr != nil {
+				return httpErr
+			}
+			if errors.Is(err, store.ErrVersionConflict) {
+				return echo.NewHTTPError(http.StatusConflict, "Recipe was modified concurrently; fetch it again and retry")
+			}
+			if errors.Is(err, primitive.ErrInvalidHex) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid recipe ID")
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "Could not update recipe")
+		}
+
+		if updatedRecipe == nil {
+			// No document was found with the provided ID.
+			return echo.NewHTTPError(http.StatusNotFound, "No recipe found with the given ID")
+		}
+
+		cache.InvalidateRecipe(updatedRecipe.ObjectID.Hex())
+		searchIndex.Put(search.RecipeDocument(*updatedRecipe))
+		writes.touch(recipesKey, recipeKey(updatedRecipe.ObjectID.Hex()))
+
+		recipe, err := resolveRecipe(context.TODO(), repository.NewIngredientRepository(s), *updatedRecipe)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "unable to resolve recipe ingredients")
+		}
+		// Hand out the new validator so the client can chain edits.
+		if tag, err := etag(recipe); err == nil {
+			c.Response().Header().Set("ETag", tag)
+		}
+
+		// Return the updated recipe and a success message.
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message": "Recipe successfully updated",
+			"recipe":  recipe,
+		})
+	}
+	e.PUT("/recipes/:id", updateRecipe)
+	e.PATCH("/recipes/:id", updateRecipe)
+
+	e.GET("/pantry", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+
+		items, err := repository.NewPantryRepository(s).List(context.TODO(), userID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "unable to fetch pantry")
+		}
+		return c.JSON(http.StatusOK, items)
+	})
+
+	e.GET("/pantry/expiring", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+
+		// Items that expire within this many days, or already have.
+		days := 3
+		if param := c.QueryParam("days"); param != "" {
+			days, err = strconv.Atoi(param)
+			if err != nil || days < 0 || days > 365 {
+				return echo.NewHTTPError(http.StatusBadRequest, "days must be between 0 and 365")
+			}
+		}
+
+		items, err := repository.NewPantryRepository(s).Expiring(context.TODO(), userID, time.Duration(days)*24*time.Hour)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "unable to fetch pantry")
+		}
+		return c.JSON(http.StatusOK, items)
+	})
+
+	e.GET("/pantry/:id", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+
+		item, err := repository.NewPantryRepository(s).FindByID(context.TODO(), userID, c.Param("id"))
+		if err != nil {
+			if errors.Is(err, primitive.ErrInvalidHex) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid pantry item ID")
+			}
+			if errors.Is(err, store.ErrNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "No pantry item found with the given ID")
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "unable to fetch pantry item")
+		}
+		return c.JSON(http.StatusOK, item)
+	})
+
+	e.POST("/pantry", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+
+		type pantryItem struct {
+			IngredientID string  `json:"IngredientID"`
+			Quantity     float64 `json:"Quantity"`
+			Unit         string  `json:"Unit"`
+			// PurchasedAt defaults to today; ExpiresAt is optional.
+			PurchasedAt string `json:"PurchasedAt"`
+			ExpiresAt   string `json:"ExpiresAt"`
+		}
+		var newItems []pantryItem
+
+		// Bind the request body to newItems slice
+		if err := c.Bind(&newItems); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid input")
+		}
+
+		docs := make([]model.PantryItem, 0, len(newItems))
+		for _, item := range newItems {
+			if item.Quantity < 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, "Quantity must not be negative")
+			}
+			unit, err := normalizeUnit(item.Unit)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			doc := model.PantryItem{IngredientID: item.IngredientID, Quantity: item.Quantity, Unit: unit}
+
+			doc.PurchasedAt = time.Now().UTC().Truncate(24 * time.Hour)
+			if item.PurchasedAt != "" {
+				if doc.PurchasedAt, err = parseDate(item.PurchasedAt); err != nil {
+					return echo.NewHTTPError(http.StatusBadRequest, "PurchasedAt: "+err.Error())
+				}
+			}
+			if item.ExpiresAt != "" {
+				expiresAt, err := parseDate(item.ExpiresAt)
+				if err != nil {
+					return echo.NewHTTPError(http.StatusBadRequest, "ExpiresAt: "+err.Error())
+				}
+				doc.ExpiresAt = &expiresAt
+			}
+			docs = append(docs, doc)
+		}
+
+		insertedIDs, err := repository.NewPantryRepository(s).Insert(context.TODO(), userID, docs)
+		if err != nil {
+			if errors.Is(err, primitive.ErrInvalidHex) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid IngredientID")
+			}
+			if errors.Is(err, repository.ErrUnknownIngredient) {
+				return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to insert pantry items")
+		}
+		return c.JSON(http.StatusCreated, insertedIDs)
+	})
+
+	e.PUT("/pantry/:id", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+
+		// Only the provided fields are updated.
+		type updateRequest struct {
+			Quantity    *float64 `json:"Quantity,omitempty"`
+			Unit        *string  `json:"Unit,omitempty"`
+			PurchasedAt *string  `json:"PurchasedAt,omitempty"`
+			// ExpiresAt is cleared by sending null.
+			ExpiresAt nullableDate `json:"ExpiresAt"`
+		}
+		var updateData updateRequest
+
+		// Bind the request body to the struct.
+		if err := c.Bind(&updateData); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid input")
+		}
+
+		update := model.PantryItemUpdate{Quantity: updateData.Quantity}
+		if update.Quantity != nil && *update.Quantity < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Quantity must not be negative")
+		}
+		if updateData.Unit != nil {
+			unit, err := normalizeUnit(*updateData.Unit)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			update.Unit = &unit
+		}
+		if updateData.PurchasedAt != nil {
+			purchasedAt, err := parseDate(*updateData.PurchasedAt)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "PurchasedAt: "+err.Error())
+			}
+			update.PurchasedAt = &purchasedAt
+		}
+		if updateData.ExpiresAt.Set {
+			if updateData.ExpiresAt.Value == nil {
+				update.ClearExpiresAt = true
+			} else {
+				expiresAt, err := parseDate(*updateData.ExpiresAt.Value)
+				if err != nil {
+					return echo.NewHTTPError(http.StatusBadRequest, "ExpiresAt: "+err.Error())
+				}
+				update.ExpiresAt = &expiresAt
+			}
+		}
+
+		updatedItem, err := repository.NewPantryRepository(s).UpdateByID(context.TODO(), userID, c.Param("id"), update)
+		if err != nil {
+			if errors.Is(err, primitive.ErrInvalidHex) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid pantry item ID")
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "Could not update pantry item")
+		}
+		if updatedItem == nil {
+			// No item was found with the provided ID.
+			return echo.NewHTTPError(http.StatusNotFound, "No pantry item found with the given ID")
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message": "Pantry item successfully updated",
+			"item":    updatedItem,
+		})
+	})
+
+	e.DELETE("/pantry/:id", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+		id := c.Param("id")
+
+		deletedCount, err := repository.NewPantryRepository(s).DeleteByID(context.TODO(), userID, id)
+		if err != nil {
+			if errors.Is(err, primitive.ErrInvalidHex) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid pantry item ID")
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "Could not delete pantry item")
+		}
+		if deletedCount == 0 {
+			return echo.NewHTTPError(http.StatusNotFound, "No pantry item found with the given ID")
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message": "Pantry item successfully deleted",
+			"id":      id,
+		})
+	})
+
+	e.POST("/shopping-lists/generate", func(c echo.Context) error {
+		type selection struct {
+			RecipeID string `json:"RecipeID"`
+			// Servings defaults to the recipe's own serving count.
+			Servings int `json:"Servings"`
+		}
+		var selections []selection
+
+		// Bind the request body to selections slice
+		if err := c.Bind(&selections); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid input")
+		}
+		if len(selections) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "No recipes selected")
+		}
+
+		system := units.Metric
+		if param := c.QueryParam("system"); param != "" {
+			var ok bool
+			if system, ok = units.ParseSystem(param); !ok {
+				return echo.NewHTTPError(http.StatusBadRequest, "system must be metric or imperial")
+			}
+		}
+
+		ids := make([]string, 0, len(selections))
+		for _, selected := range selections {
+			objID, err := primitive.ObjectIDFromHex(selected.RecipeID)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid RecipeID %q", selected.RecipeID))
+			}
+			if selected.Servings < 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, "Servings must not be negative")
+			}
+			ids = append(ids, objID.Hex())
+		}
+
+		recipes, err := getRecipes(s, ids)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "unable to fetch recipes")
+		}
+		byID := make(map[string]model.Recipe, len(recipes))
+		for _, recipe := range recipes {
+			byID[recipe.ObjectID.Hex()] = recipe
+		}
+		portions := make([]shopping.Portion, 0, len(selections))
+		var unknown []string
+		for i, id := range ids {
+			recipe, ok := byID[id]
+			if !ok {
+				unknown = append(unknown, id)
+				continue
+			}
+			portions = append(portions, shopping.Portion{Recipe: recipe, Servings: selections[i].Servings})
+		}
+		if len(unknown) > 0 {
+			return echo.NewHTTPError(http.StatusNotFound, map[string]interface{}{
+				"message": "No recipe found with the given IDs",
+				"ids":     unknown,
+			})
+		}
+
+		// Subtract what the user already has when a pantry is given.
+		var stock []model.PantryItem
+		if userID := c.QueryParam("userId"); userID != "" {
+			stock, err = repository.NewPantryRepository(s).List(context.TODO(), userID)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "unable to fetch pantry")
+			}
+		}
+
+		return c.JSON(http.StatusOK, shopping.Build(portions, stock, system))
+	})
+
+	e.GET("/shopping-lists", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+
+		lists, err := repository.NewShoppingListRepository(s).List(context.TODO(), userID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "unable to fetch shopping lists")
+		}
+		return c.JSON(http.StatusOK, lists)
+	})
+
+	e.GET("/shopping-lists/:id", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+
+		list, err := repository.NewShoppingListRepository(s).FindByID(context.TODO(), userID, c.Param("id"))
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "No shopping list found with the given ID")
+			}
+			return shoppingListError(err, "unable to fetch shopping list")
+		}
+		return c.JSON(http.StatusOK, list)
+	})
+
+	e.POST("/shopping-lists", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+
+		type newList struct {
+			Name    string             `json:"Name"`
+			Members []string           `json:"Members"`
+			Items   []shoppingListItem `json:"Items"`
+		}
+		var request newList
+
+		// Bind the request body to the struct.
+		if err := c.Bind(&request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid input")
+		}
+		name := strings.TrimSpace(request.Name)
+		if name == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "Name is required")
+		}
+		items, err := shoppingListItems(request.Items)
+		if err != nil {
+			return err
+		}
+
+		list, err := repository.NewShoppingListRepository(s).Create(context.TODO(), userID,
+			model.ShoppingList{Name: name, Members: request.Members, Items: items})
+		if err != nil {
+			return shoppingListError(err, "Failed to insert shopping list")
+		}
+		publishShoppingList(ShoppingListCreated, list, "")
+		return c.JSON(http.StatusCreated, list)
+	})
+
+	e.PUT("/shopping-lists/:id", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+
+		// Only the provided fields are updated.
+		type updateRequest struct {
+			Name    *string   `json:"Name,omitempty"`
+			Members *[]string `json:"Members,omitempty"`
+		}
+		var updateData updateRequest
+
+		// Bind the request body to the struct.
+		if err := c.Bind(&updateData); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid input")
+		}
+		update := model.ShoppingListUpdate{Members: updateData.Members}
+		if updateData.Name != nil {
+			name := strings.TrimSpace(*updateData.Name)
+			if name == "" {
+				return echo.NewHTTPError(http.StatusBadRequest, "Name must not be empty")
+			}
+			update.Name = &name
+		}
+
+		repo := repository.NewShoppingListRepository(s)
+		// Members being removed are told too, so their clients drop the list.
+		var previousMembers []string
+		if update.Members != nil {
+			if previous, err := repo.FindByID(context.TODO(), userID, c.Param("id")); err == nil {
+				previousMembers = previous.Members
+			}
+		}
+
+		updatedList, err := repo.UpdateByID(context.TODO(), userID, c.Param("id"), update)
+		if err != nil {
+			return shoppingListError(err, "Could not update shopping list")
+		}
+		if updatedList == nil {
+			// No list was found with the provided ID.
+			return echo.NewHTTPError(http.StatusNotFound, "No shopping list found with the given ID")
+		}
+		publishShoppingList(ShoppingListUpdated, updatedList, "", previousMembers...)
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message": "Shopping list successfully updated",
+			"list":    updatedList,
+		})
+	})
+
+	e.DELETE("/shopping-lists/:id", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+		id := c.Param("id")
+
+		deletedList, err := repository.NewShoppingListRepository(s).DeleteByID(context.TODO(), userID, id)
+		if err != nil {
+			return shoppingListError(err, "Could not delete shopping list")
+		}
+		if deletedList == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "No shopping list found with the given ID")
+		}
+		publishShoppingList(ShoppingListDeleted, deletedList, "")
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message": "Shopping list successfully deleted",
+			"id":      id,
+		})
+	})
+
+	e.POST("/shopping-lists/:id/items", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+
+		var newItems []shoppingListItem
+
+		// Bind the request body to newItems slice
+		if err := c.Bind(&newItems); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid input")
+		}
+		if len(newItems) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "No items given")
+		}
+		items, err := shoppingListItems(newItems)
+		if err != nil {
+			return err
+		}
+
+		updatedList, err := repository.NewShoppingListRepository(s).AddItems(context.TODO(), userID, c.Param("id"), items)
+		if err != nil {
+			return shoppingListError(err, "Failed to add shopping list items")
+		}
+		if updatedList == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "No shopping list found with the given ID")
+		}
+		publishShoppingList(ShoppingListItemsAdded, updatedList, "")
+		return c.JSON(http.StatusCreated, updatedList)
+	})
+
+	e.PUT("/shopping-lists/:id/items/:itemId", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+
+		// Only the provided fields are updated; checking an item off is
+		// {"Checked": true}.
+		type updateRequest struct {
+			Name     *string  `json:"Name,omitempty"`
+			Quantity *float64 `json:"Quantity,omitempty"`
+			Unit     *string  `json:"Unit,omitempty"`
+			Checked  *bool    `json:"Checked,omitempty"`
+		}
+		var updateData updateRequest
+
+		// Bind the request body to the struct.
+		if err := c.Bind(&updateData); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid input")
+		}
+		update := model.ShoppingListItemUpdate{Quantity: updateData.Quantity, Checked: updateData.Checked}
+		if updateData.Name != nil {
+			name := strings.TrimSpace(*updateData.Name)
+			if name == "" {
+				return echo.NewHTTPError(http.StatusBadRequest, "Name must not be empty")
+			}
+			update.Name = &name
+		}
+		if update.Quantity != nil && *update.Quantity < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Quantity must not be negative")
+		}
+		if updateData.Unit != nil {
+			unit := listUnit(*updateData.Unit)
+			update.Unit = &unit
+		}
+
+		itemID := c.Param("itemId")
+		updatedList, err := repository.NewShoppingListRepository(s).UpdateItem(context.TODO(), userID, c.Param("id"), itemID, update)
+		if err != nil {
+			return shoppingListError(err, "Could not update shopping list item")
+		}
+		if updatedList == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "No shopping list item found with the given IDs")
+		}
+		publishShoppingList(ShoppingListItemUpdated, updatedList, itemID)
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message": "Shopping list item successfully updated",
+			"list":    updatedList,
+		})
+	})
+
+	e.DELETE("/shopping-lists/:id/items/:itemId", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+
+		itemID := c.Param("itemId")
+		updatedList, err := repository.NewShoppingListRepository(s).RemoveItem(context.TODO(), userID, c.Param("id"), itemID)
+		if err != nil {
+			return shoppingListError(err, "Could not delete shopping list item")
+		}
+		if updatedList == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "No shopping list item found with the given IDs")
+		}
+		publishShoppingList(ShoppingListItemRemoved, updatedList, itemID)
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message": "Shopping list item successfully deleted",
+			"list":    updatedList,
+		})
+	})
+
+	e.GET("/meal-plan", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+		from, to, err := planRange(c)
+		if err != nil {
+			return err
+		}
+
+		plan, err := loadMealPlan(s, userID, from, to)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "unable to fetch meal plan")
+		}
+		return c.JSON(http.StatusOK, plan)
+	})
+
+	e.POST("/meal-plan", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+
+		type mealPlanEntry struct {
+			Date     string `json:"Date"`
+			Slot     string `json:"Slot"`
+			RecipeID string `json:"RecipeID"`
+			// Servings defaults to 1.
+			Servings int `json:"Servings"`
+		}
+		var newEntries []mealPlanEntry
+
+		// Bind the request body to newEntries slice
+		if err := c.Bind(&newEntries); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid input")
+		}
+
+		docs := make([]model.MealPlanEntry, 0, len(newEntries))
+		for _, entry := range newEntries {
+			date, err := parseDate(entry.Date)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "Date: "+err.Error())
+			}
+			slot, err := parseSlot(entry.Slot)
+			if err != nil {
+				return err
+			}
+			if entry.Servings < 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, "Servings must not be negative")
+			}
+			if entry.Servings == 0 {
+				entry.Servings = 1
+			}
+			docs = append(docs, model.MealPlanEntry{
+				Date:     date.Truncate(mealplan.Day),
+				Slot:     slot,
+				RecipeID: entry.RecipeID,
+				Servings: entry.Servings,
+			})
+		}
+
+		insertedIDs, err := repository.NewMealPlanRepository(s).Insert(context.TODO(), userID, docs)
+		if err != nil {
+			if errors.Is(err, primitive.ErrInvalidHex) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid RecipeID")
+			}
+			if errors.Is(err, repository.ErrUnknownRecipe) {
+				return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to insert meal plan entries")
+		}
+		return c.JSON(http.StatusCreated, insertedIDs)
+	})
+
+	e.DELETE("/meal-plan", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+		// Unlike GET, clearing requires the range to be given explicitly.
+		if c.QueryParam("week") == "" && c.QueryParam("month") == "" && c.QueryParam("from") == "" && c.QueryParam("to") == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "Give week, month or from and to")
+		}
+		from, to, err := planRange(c)
+		if err != nil {
+			return err
+		}
+
+		deletedCount, err := repository.NewMealPlanRepository(s).DeleteRange(context.TODO(), userID, from, to)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Could not clear meal plan")
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message": "Meal plan successfully cleared",
+			"deleted": deletedCount,
+		})
+	})
+
+	e.POST("/meal-plan/generate", func(c echo.Context) error {
+		type generateRequest struct {
+			// Target is the daily calorie target in kcal.
+			Target float64 `json:"Target"`
+			// From defaults to today and Days to a week.
+			From               string   `json:"From"`
+			Days               int      `json:"Days"`
+			Slots              []string `json:"Slots"`
+			ExcludeIngredients []string `json:"ExcludeIngredients"`
+			MaxRepeatsPerWeek  int      `json:"MaxRepeatsPerWeek"`
+			// MaxServings is between 1 and 10; 0 or leaving it out
+			// selects mealplan.DefaultMaxServings.
+			MaxServings int `json:"MaxServings"`
+			// Seed defaults to a random one, returned with the plan so
+			// that it can be generated again.
+			Seed *int64 `json:"Seed"`
+		}
+		var request generateRequest
+
+		// Bind the request body to the struct.
+		if err := c.Bind(&request); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid input")
+		}
+		if request.Target <= 0 || request.Target > 20000 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Target must be between 0 and 20000 kcal")
+		}
+		if request.Days == 0 {
+			request.Days = 7
+		}
+		if request.Days < 1 || request.Days > 31 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Days must be between 1 and 31")
+		}
+		if request.MaxRepeatsPerWeek < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "MaxRepeatsPerWeek must not be negative")
+		}
+		if request.MaxServings < 0 || request.MaxServings > 10 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("MaxServings must be between 1 and 10, or 0 for the default of %d", mealplan.DefaultMaxServings))
+		}
+
+		opts := mealplan.Options{
+			Target:            request.Target,
+			MaxRepeatsPerWeek: request.MaxRepeatsPerWeek,
+			MaxServings:       request.MaxServings,
+			Seed:              time.Now().UnixNano(),
+		}
+		if request.Seed != nil {
+			opts.Seed = *request.Seed
+		}
+		from := time.Now().UTC()
+		if request.From != "" {
+			var err error
+			if from, err = parseDate(request.From); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "From: "+err.Error())
+			}
+		}
+		for _, value := range request.Slots {
+			slot, err := parseSlot(value)
+			if err != nil {
+				return err
+			}
+			if !slices.Contains(opts.Slots, slot) {
+				opts.Slots = append(opts.Slots, slot)
+			}
+		}
+		for _, id := range request.ExcludeIngredients {
+			objID, err := primitive.ObjectIDFromHex(id)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid ingredient ID %q", id))
+			}
+			opts.ExcludedIngredients = append(opts.ExcludedIngredients, objID.Hex())
+		}
+
+		recipes, err := getAllRecipes(s)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "unable to fetch recipes")
+		}
+		return c.JSON(http.StatusOK, mealplan.Generate(recipes, from, request.Days, opts))
+	})
+
+	e.GET("/meal-plan/:id", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+
+		entry, err := repository.NewMealPlanRepository(s).FindByID(context.TODO(), userID, c.Param("id"))
+		if err != nil {
+			if errors.Is(err, primitive.ErrInvalidHex) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid meal plan entry ID")
+			}
+			if errors.Is(err, store.ErrNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "No meal plan entry found with the given ID")
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "unable to fetch meal plan entry")
+		}
+		return c.JSON(http.StatusOK, entry)
+	})
+
+	e.PUT("/meal-plan/:id", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+
+		// Only the provided fields are updated.
+		type updateRequest struct {
+			Date     *string `json:"Date,omitempty"`
+			Slot     *string `json:"Slot,omitempty"`
+			RecipeID *string `json:"RecipeID,omitempty"`
+			Servings *int    `json:"Servings,omitempty"`
+		}
+		var updateData updateRequest
+
+		// Bind the request body to the struct.
+		if err := c.Bind(&updateData); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid input")
+		}
+
+		update := model.MealPlanEntryUpdate{RecipeID: updateData.RecipeID, Servings: updateData.Servings}
+		if updateData.Date != nil {
+			date, err := parseDate(*updateData.Date)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "Date: "+err.Error())
+			}
+			date = date.Truncate(mealplan.Day)
+			update.Date = &date
+		}
+		if updateData.Slot != nil {
+			slot, err := parseSlot(*updateData.Slot)
+			if err != nil {
+				return err
+			}
+			update.Slot = &slot
+		}
+		if update.Servings != nil && *update.Servings < 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Servings must be at least 1")
+		}
+
+		updatedEntry, err := repository.NewMealPlanRepository(s).UpdateByID(context.TODO(), userID, c.Param("id"), update)
+		if err != nil {
+			if errors.Is(err, primitive.ErrInvalidHex) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid meal plan entry or recipe ID")
+			}
+			if errors.Is(err, repository.ErrUnknownRecipe) {
+				return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "Could not update meal plan entry")
+		}
+		if updatedEntry == nil {
+			// No entry was found with the provided ID.
+			return echo.NewHTTPError(http.StatusNotFound, "No meal plan entry found with the given ID")
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message": "Meal plan entry successfully updated",
+			"entry":   updatedEntry,
+		})
+	})
+
+	e.DELETE("/meal-plan/:id", func(c echo.Context) error {
+		userID, err := userIDParam(c)
+		if err != nil {
+			return err
+		}
+		id := c.Param("id")
+
+		deletedCount, err := repository.NewMealPlanRepository(s).DeleteByID(context.TODO(), userID, id)
+		if err != nil {
+			if errors.Is(err, primitive.ErrInvalidHex) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid meal plan entry ID")
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "Could not delete meal plan entry")
+		}
+		if deletedCount == 0 {
+			return echo.NewHTTPError(http.StatusNotFound, "No meal plan entry found with the given ID")
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message": "Meal plan entry successfully deleted",
+			"id":      id,
+		})
+	})
+
+	e.GET("/ws", HandleWebSocketConnection)
+
+	e.GET("/search", handleSearch)
+	e.GET("/ingredients/suggest", handleSuggest)
+
+	// Cache statistics reveal what is being read, so they are only served
+	// when DEBUG_ENDPOINTS is set.
+	if debug, _ := strconv.ParseBool(os.Getenv("DEBUG_ENDPOINTS")); debug {
+		e.GET("/debug/cache", func(c echo.Context) error {
+			return c.JSON(http.StatusOK, map[string]cache.Stats{
+				"ingredients": cache.Ingredients.Stats(),
+				"recipes":     cache.Recipes.Stats(),
+				"lists":       cache.Lists.Stats(),
+			})
+		})
+	}
 
 	// e.GET("/debug/pprof/*", echo.WrapHandler(http.DefaultServeMux))
 
+	return nil
 }
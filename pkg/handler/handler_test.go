@@ -0,0 +1,300 @@
+package handler
+
+import (
+	"context"
+	"dynamicrecipes/pkg/cache"
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/repository"
+	"dynamicrecipes/pkg/store"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTestServer serves the routes over s. The list caches are shared by all
+// servers in the process, so they are emptied first.
+func newTestServer(t *testing.T, s store.Store) *echo.Echo {
+	t.Helper()
+	cache.Lists.Purge()
+	e := echo.New()
+	e.Logger.SetOutput(io.Discard)
+	if err := InitRoutes(e, s); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// serve sends a request with an optional JSON body and headers given as
+// name, value pairs.
+func serve(e *echo.Echo, method, target string, body any, headers ...string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			panic(err)
+		}
+		reader = strings.NewReader(string(b))
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if body != nil {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// expectStatus fails the test unless rec has the wanted status.
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("status %d, want %d; body: %s", rec.Code, want, rec.Body.String())
+	}
+}
+
+// seedPancakes stores flour and a pancake recipe using it.
+func seedPancakes(t *testing.T, s store.Store) (flour, pancakes primitive.ObjectID) {
+	t.Helper()
+	ctx := context.Background()
+	ingredientIDs, err := s.Ingredients().InsertMany(ctx, []model.Ingredient{{Name: "flour " + primitive.NewObjectID().Hex(), Calories: 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipeIDs, err := s.Recipes().InsertMany(ctx, []model.RecipePostType{{
+		Name:        "pancakes",
+		Servings:    2,
+		Ingredients: []model.IngredientIDType{{ObjectID: ingredientIDs[0].Hex(), Quantity: 200, Unit: "g"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ingredientIDs[0], recipeIDs[0]
+}
+
+// countingStore counts the ingredient lookups made through it, each of which
+// is a round trip to the database with a real backend, and can delay them to
+// stand in for the network.
+type countingStore struct {
+	store.Store
+	ingredients *countingIngredientStore
+}
+
+func (s *countingStore) Ingredients() store.IngredientStore { return s.ingredients }
+
+type countingIngredientStore struct {
+	store.IngredientStore
+	latency time.Duration
+	calls   atomic.Int64
+}
+
+func (s *countingIngredientStore) roundTrip() {
+	s.calls.Add(1)
+	if s.latency > 0 {
+		time.Sleep(s.latency)
+	}
+}
+
+func (s *countingIngredientStore) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Ingredient, error) {
+	s.roundTrip()
+	return s.IngredientStore.FindByID(ctx, id)
+}
+
+func (s *countingIngredientStore) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.Ingredient, error) {
+	s.roundTrip()
+	return s.IngredientStore.FindByIDs(ctx, ids)
+}
+
+// seedRecipes fills a MemoryStore with recipes recipes of perRecipe lines
+// each, drawn from a shared set of ingredients.
+func seedRecipes(tb testing.TB, recipes, perRecipe int) (store.Store, []model.RecipeReturnType) {
+	tb.Helper()
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+
+	ingredients := make([]model.Ingredient, 50)
+	for i := range ingredients {
+		ingredients[i] = model.Ingredient{Name: fmt.Sprintf("ingredient %d", i), Calories: 2, Density: 1}
+	}
+	ingredientIDs, err := s.Ingredients().InsertMany(ctx, ingredients)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	docs := make([]model.RecipePostType, recipes)
+	for i := range docs {
+		docs[i] = model.RecipePostType{Name: fmt.Sprintf("recipe %d", i), Servings: 2}
+		for j := 0; j < perRecipe; j++ {
+			id := ingredientIDs[(i*7+j*3)%len(ingredientIDs)]
+			docs[i].Ingredients = append(docs[i].Ingredients, model.IngredientIDType{ObjectID: id.Hex(), Quantity: 100, Unit: "g"})
+		}
+	}
+	if _, err := s.Recipes().InsertMany(ctx, docs); err != nil {
+		tb.Fatal(err)
+	}
+	stored, err := s.Recipes().List(ctx)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return s, stored
+}
+
+// resolveRecipesPerIngredient is how recipes were resolved before
+// resolveRecipes: one goroutine per recipe, each looking up its ingredients
+// one at a time.
+func resolveRecipesPerIngredient(ctx context.Context, ingredientRepo *repository.IngredientRepository, recipeItems []model.RecipeReturnType) ([]model.Recipe, error) {
+	errChan := make(chan error, 1)
+	var wg sync.WaitGroup
+
+	recipes := make([]model.Recipe, len(recipeItems))
+	for i, recipeItem := range recipeItems {
+		wg.Add(1)
+		go func(i int, recipeItem model.RecipeReturnType) {
+			defer wg.Done()
+
+			var ingredientsResponse []model.RecipeIngredient
+			for _, id := range recipeItem.ID {
+				ingredient, err := ingredientRepo.FindByID(ctx, id.ObjectID)
+				if err != nil {
+					select {
+					case errChan <- err:
+					default:
+					}
+					return
+				}
+				ingredientsResponse = append(ingredientsResponse, model.RecipeIngredient{Ingredient: *ingredient, Quantity: id.Quantity, Unit: id.Unit})
+			}
+			recipes[i] = model.Recipe{ObjectID: recipeItem.ObjectID, Name: recipeItem.Name, Servings: recipeItem.Servings, Ingredients: ingredientsResponse}
+		}(i, recipeItem)
+	}
+	wg.Wait()
+	close(errChan)
+
+	if err := <-errChan; err != nil {
+		return nil, err
+	}
+	return recipes, nil
+}
+
+// BenchmarkResolveRecipes compares resolving a few hundred recipes with one
+// batched lookup against the previous lookup per ingredient line. lookups/op
+// is the number of database round trips; the latency variants show what they
+// cost against a database that is not in process.
+func BenchmarkResolveRecipes(b *testing.B) {
+	resolvers := []struct {
+		name    string
+		resolve func(context.Context, *repository.IngredientRepository, []model.RecipeReturnType) ([]model.Recipe, error)
+	}{
+		{"batched", resolveRecipes},
+		{"perIngredient", resolveRecipesPerIngredient},
+	}
+	memory, recipes := seedRecipes(b, 300, 8)
+
+	for _, latency := range []time.Duration{0, 100 * time.Microsecond} {
+		for _, resolver := range resolvers {
+			b.Run(fmt.Sprintf("%s/latency=%s", resolver.name, latency), func(b *testing.B) {
+				ingredients := &countingIngredientStore{IngredientStore: memory.Ingredients(), latency: latency}
+				repo := repository.NewIngredientRepository(&countingStore{Store: memory, ingredients: ingredients})
+				ctx := context.Background()
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					resolved, err := resolver.resolve(ctx, repo, recipes)
+					if err != nil {
+						b.Fatal(err)
+					}
+					if len(resolved) != len(recipes) {
+						b.Fatalf("resolved %d recipes, want %d", len(resolved), len(recipes))
+					}
+				}
+				b.ReportMetric(float64(ingredients.calls.Load())/float64(b.N), "lookups/op")
+			})
+		}
+	}
+}
+
+func TestResolveRecipesMatchesPerIngredient(t *testing.T) {
+	s, recipes := seedRecipes(t, 20, 5)
+	repo := repository.NewIngredientRepository(s)
+
+	batched, err := resolveRecipes(context.Background(), repo, recipes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	perIngredient, err := resolveRecipesPerIngredient(context.Background(), repo, recipes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range batched {
+		got, want := batched[i].Ingredients, perIngredient[i].Ingredients
+		if len(got) != len(want) {
+			t.Fatalf("recipe %d has %d ingredients, want %d", i, len(got), len(want))
+		}
+		for j := range got {
+			if got[j].ObjectID != want[j].ObjectID || got[j].Quantity != want[j].Quantity {
+				t.Errorf("recipe %d line %d is %+v, want %+v", i, j, got[j], want[j])
+			}
+		}
+	}
+}
+
+func TestDebugCacheNeedsDebugEndpoints(t *testing.T) {
+	for _, tt := range []struct {
+		env    string
+		status int
+	}{
+		{"", http.StatusNotFound},
+		{"false", http.StatusNotFound},
+		{"true", http.StatusOK},
+	} {
+		t.Setenv("DEBUG_ENDPOINTS", tt.env)
+		e := newTestServer(t, store.NewMemoryStore())
+		if rec := serve(e, http.MethodGet, "/debug/cache", nil); rec.Code != tt.status {
+			t.Errorf("DEBUG_ENDPOINTS=%q: status %d, want %d", tt.env, rec.Code, tt.status)
+		}
+	}
+}
+
+func TestIngredientUpdateInvalidatesCachedRecipes(t *testing.T) {
+	s := store.NewMemoryStore()
+	flour, pancakes := seedPancakes(t, s)
+	e := newTestServer(t, s)
+
+	calories := func() float64 {
+		t.Helper()
+		rec := serve(e, http.MethodGet, "/recipes/"+pancakes.Hex(), nil)
+		expectStatus(t, rec, http.StatusOK)
+		var recipe model.Recipe
+		if err := json.Unmarshal(rec.Body.Bytes(), &recipe); err != nil {
+			t.Fatal(err)
+		}
+		return recipe.Nutrition.Total.Calories
+	}
+	if got := calories(); got != 800 {
+		t.Fatalf("pancakes have %g kcal, want 800", got)
+	}
+	if _, ok := cache.Recipes.Get(pancakes.Hex()); !ok {
+		t.Fatal("the recipe was not cached")
+	}
+
+	rec := serve(e, http.MethodPut, "/ingredients/"+flour.Hex(), map[string]any{"calories": 5}, "If-Match", "*")
+	expectStatus(t, rec, http.StatusOK)
+	if _, ok := cache.Recipes.Get(pancakes.Hex()); ok {
+		t.Error("the recipe is still cached after its ingredient changed")
+	}
+	if got := calories(); got != 1000 {
+		t.Errorf("pancakes have %g kcal after the update, want 1000", got)
+	}
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"dynamicrecipes/pkg/mealplan"
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/repository"
+	"dynamicrecipes/pkg/store"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxPlanDays caps the range of a from/to meal plan query.
+const maxPlanDays = 93
+
+// planRange returns the days selected by the week, month or from and to
+// query parameters, as the first day and the day after the last. A week is
+// given as an ISO week (2024-W07) or any date within it, a month as 2024-02,
+// and from and to as dates, to included. Without any, it is the current
+// week.
+func planRange(c echo.Context) (from, to time.Time, err error) {
+	week, month := c.QueryParam("week"), c.QueryParam("month")
+	fromParam, toParam := c.QueryParam("from"), c.QueryParam("to")
+
+	given := 0
+	for _, param := range []string{week, month, fromParam + toParam} {
+		if param != "" {
+			given++
+		}
+	}
+	if given > 1 {
+		return from, to, echo.NewHTTPError(http.StatusBadRequest, "Give only one of week, month or from and to")
+	}
+
+	switch {
+	case week != "":
+		day, err := parseWeek(week)
+		if err != nil {
+			return from, to, echo.NewHTTPError(http.StatusBadRequest, "week: "+err.Error())
+		}
+		from, to = mealplan.Week(day)
+	case month != "":
+		day, err := time.Parse("2006-01", month)
+		if err != nil {
+			return from, to, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid month %q; use YYYY-MM", month))
+		}
+		from, to = mealplan.Month(day)
+	case fromParam != "" || toParam != "":
+		if fromParam == "" || toParam == "" {
+			return from, to, echo.NewHTTPError(http.StatusBadRequest, "from and to must be given together")
+		}
+		if from, err = parseDate(fromParam); err != nil {
+			return from, to, echo.NewHTTPError(http.StatusBadRequest, "from: "+err.Error())
+		}
+		last, err := parseDate(toParam)
+		if err != nil {
+			return from, to, echo.NewHTTPError(http.StatusBadRequest, "to: "+err.Error())
+		}
+		from, to = from.Truncate(mealplan.Day), last.Truncate(mealplan.Day).Add(mealplan.Day)
+		if !from.Before(to) || to.Sub(from) > maxPlanDays*mealplan.Day {
+			return from, to, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("to must be after from and at most %d days later", maxPlanDays-1))
+		}
+	default:
+		from, to = mealplan.Week(time.Now())
+	}
+	return from, to, nil
+}
+
+// parseWeek returns the Monday of an ISO week (2024-W07), or the date given
+// for any other day of a week.
+func parseWeek(value string) (time.Time, error) {
+	var year, week int
+	if _, err := fmt.Sscanf(value, "%d-W%d", &year, &week); err != nil || !strings.Contains(value, "-W") {
+		return parseDate(value)
+	}
+	// January 4th is always in week 1.
+	monday, _ := mealplan.Week(time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC))
+	monday = monday.AddDate(0, 0, 7*(week-1))
+	if y, w := monday.ISOWeek(); week < 1 || y != year || w != week {
+		return time.Time{}, fmt.Errorf("%q is not a week of %d", value, year)
+	}
+	return monday, nil
+}
+
+// parseSlot returns the canonical form of a meal slot.
+func parseSlot(value string) (string, error) {
+	slot := strings.ToLower(strings.TrimSpace(value))
+	if !slices.Contains(model.MealSlots, slot) {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Slot must be one of "+strings.Join(model.MealSlots, ", "))
+	}
+	return slot, nil
+}
+
+// loadMealPlan builds the user's meal plan for the given days, with the
+// planned recipes loaded through the recipe cache.
+func loadMealPlan(s store.Store, userID string, from, to time.Time) (model.MealPlan, error) {
+	entries, err := repository.NewMealPlanRepository(s).List(context.TODO(), userID, from, to)
+	if err != nil {
+		return model.MealPlan{}, err
+	}
+
+	var ids []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if !seen[entry.RecipeID] {
+			seen[entry.RecipeID] = true
+			ids = append(ids, entry.RecipeID)
+		}
+	}
+	recipes, err := getRecipes(s, ids)
+	if err != nil {
+		return model.MealPlan{}, err
+	}
+	byID := make(map[string]model.Recipe, len(recipes))
+	for _, recipe := range recipes {
+		byID[recipe.ObjectID.Hex()] = recipe
+	}
+	return mealplan.Build(from, to, entries, byID), nil
+}
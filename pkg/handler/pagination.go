@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"dynamicrecipes/pkg/repository"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// nextCursorHeader carries the cursor of the next page of a paged listing. It
+// is absent on the last page, and the body stays a plain JSON array.
+const nextCursorHeader = "X-Next-Cursor"
+
+// listParams reads the paging query parameters: limit, cursor, sort, prefix,
+// minCalories and maxCalories. paged is false when none of them is given, in
+// which case listings keep returning the whole collection.
+func listParams(c echo.Context) (params repository.ListParams, paged bool, err error) {
+	intParam := func(name string, dst **int) {
+		value := c.QueryParam(name)
+		if value == "" || err != nil {
+			return
+		}
+		n, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			err = echo.NewHTTPError(http.StatusBadRequest, name+" must be an integer")
+			return
+		}
+		*dst = &n
+	}
+
+	var limit *int
+	intParam("limit", &limit)
+	intParam("minCalories", &params.MinCalories)
+	intParam("maxCalories", &params.MaxCalories)
+	if err != nil {
+		return params, false, err
+	}
+	if limit != nil {
+		if *limit <= 0 {
+			return params, false, echo.NewHTTPError(http.StatusBadRequest, "limit must be a positive integer")
+		}
+		params.Limit = *limit
+	}
+	params.Cursor = c.QueryParam("cursor")
+	params.Sort = c.QueryParam("sort")
+	params.NamePrefix = c.QueryParam("prefix")
+
+	paged = limit != nil || params.Cursor != "" || params.Sort != "" || params.NamePrefix != "" ||
+		params.MinCalories != nil || params.MaxCalories != nil
+	return params, paged, nil
+}
+
+// listError turns an error from a repository Page call into an HTTP error.
+func listError(err error, message string) error {
+	if errors.Is(err, repository.ErrInvalidListParams) {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
+
+// limitParam reads the optional limit query parameter, between 1 and max.
+func limitParam(c echo.Context, fallback, max int) (int, error) {
+	param := c.QueryParam("limit")
+	if param == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(param)
+	if err != nil || n <= 0 || n > max {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(max))
+	}
+	return n, nil
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"dynamicrecipes/pkg/store"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestListCursor(t *testing.T) {
+	for _, path := range []string{"/recipes", "/ingredients"} {
+		t.Run(path, func(t *testing.T) {
+			s := store.NewMemoryStore()
+			for i := 0; i < 3; i++ {
+				seedPancakes(t, s)
+			}
+			e := newTestServer(t, s)
+
+			// Both listings can be sorted by calories, recipes per serving.
+			seen := map[string]bool{}
+			target := path + "?limit=2&sort=-calories"
+			for pages := 0; target != ""; pages++ {
+				if pages > 5 {
+					t.Fatal("paging does not end")
+				}
+				rec := serve(e, http.MethodGet, target, nil)
+				expectStatus(t, rec, http.StatusOK)
+				var items []struct{ ObjectID string }
+				if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil {
+					t.Fatal(err)
+				}
+				for _, item := range items {
+					if seen[item.ObjectID] {
+						t.Errorf("%s listed twice", item.ObjectID)
+					}
+					seen[item.ObjectID] = true
+				}
+
+				target = ""
+				if next := rec.Header().Get(nextCursorHeader); next != "" {
+					target = path + "?limit=2&sort=-calories&cursor=" + url.QueryEscape(next)
+					for _, tampered := range []string{next[:len(next)-1] + "!", next[:len(next)/2], "garbage"} {
+						rec := serve(e, http.MethodGet, path+"?limit=2&sort=-calories&cursor="+url.QueryEscape(tampered), nil)
+						expectStatus(t, rec, http.StatusBadRequest)
+					}
+					rec := serve(e, http.MethodGet, path+"?limit=2&sort=name&cursor="+url.QueryEscape(next), nil)
+					expectStatus(t, rec, http.StatusBadRequest)
+				}
+			}
+			if len(seen) != 3 {
+				t.Errorf("listed %d items, want 3", len(seen))
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"dynamicrecipes/pkg/units"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// userIDParam returns the userId query parameter that scopes per-user
+// endpoints, the same way the WebSocket endpoint identifies users.
+func userIDParam(c echo.Context) (string, error) {
+	userID := c.QueryParam("userId")
+	if userID == "" {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "userId is required")
+	}
+	return userID, nil
+}
+
+// nullableDate is an optional date of a request body that tells an explicit
+// null, which clears the date, from an absent field, which leaves it alone.
+type nullableDate struct {
+	Set   bool
+	Value *string
+}
+
+func (d *nullableDate) UnmarshalJSON(b []byte) error {
+	d.Set = true
+	return json.Unmarshal(b, &d.Value)
+}
+
+// parseDate accepts a calendar date (2006-01-02) or an RFC 3339 timestamp and
+// returns it in UTC. Calendar dates are taken as midnight UTC.
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q; use YYYY-MM-DD", value)
+	}
+	return t.UTC(), nil
+}
+
+// normalizeUnit returns the canonical symbol of a unit, or "" for no unit.
+func normalizeUnit(unit string) (string, error) {
+	if strings.TrimSpace(unit) == "" {
+		return "", nil
+	}
+	u, ok := units.Lookup(unit)
+	if !ok {
+		return "", fmt.Errorf("unknown Unit %q", unit)
+	}
+	return u.Symbol, nil
+}
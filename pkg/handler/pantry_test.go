@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/store"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUpdatePantryItemExpiry(t *testing.T) {
+	s := store.NewMemoryStore()
+	flour, _ := seedPancakes(t, s)
+	e := newTestServer(t, s)
+
+	rec := serve(e, http.MethodPost, "/pantry?userId=ann", []map[string]any{
+		{"IngredientID": flour.Hex(), "Quantity": 1, "Unit": "kg", "ExpiresAt": "2026-11-01"},
+	})
+	expectStatus(t, rec, http.StatusCreated)
+	var ids []primitive.ObjectID
+	if err := json.Unmarshal(rec.Body.Bytes(), &ids); err != nil {
+		t.Fatal(err)
+	}
+	target := "/pantry/" + ids[0].Hex() + "?userId=ann"
+
+	date := func(s string) *time.Time {
+		d, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &d
+	}
+	tests := []struct {
+		name   string
+		body   map[string]any
+		status int
+		want   *time.Time
+	}{
+		{"other fields leave it alone", map[string]any{"Quantity": 2}, http.StatusOK, date("2026-11-01")},
+		{"a date sets it", map[string]any{"ExpiresAt": "2026-12-01"}, http.StatusOK, date("2026-12-01")},
+		{"an invalid date is rejected", map[string]any{"ExpiresAt": "soon"}, http.StatusBadRequest, date("2026-12-01")},
+		{"null clears it", map[string]any{"ExpiresAt": nil}, http.StatusOK, nil},
+		{"and it can be set again", map[string]any{"ExpiresAt": "2027-01-01"}, http.StatusOK, date("2027-01-01")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, serve(e, http.MethodPut, target, tt.body), tt.status)
+
+			rec := serve(e, http.MethodGet, target, nil)
+			expectStatus(t, rec, http.StatusOK)
+			var item model.PantryItem
+			if err := json.Unmarshal(rec.Body.Bytes(), &item); err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tt.want == nil && item.ExpiresAt != nil:
+				t.Errorf("ExpiresAt = %v, want none", item.ExpiresAt)
+			case tt.want != nil && (item.ExpiresAt == nil || !item.ExpiresAt.Equal(*tt.want)):
+				t.Errorf("ExpiresAt = %v, want %v", item.ExpiresAt, tt.want)
+			}
+		})
+	}
+}
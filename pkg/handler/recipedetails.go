@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"dynamicrecipes/pkg/model"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Limits of the recipe details a client may send.
+const (
+	maxInstructionSteps = 100
+	maxStepLength       = 2000
+	maxNotesLength      = 5000
+	// maxRecipeMinutes is a week; anything longer is a typo.
+	maxRecipeMinutes = 7 * 24 * 60
+)
+
+// recipeDetails validates the details of a new recipe and returns them
+// normalized: steps and text trimmed and the difficulty lowercased.
+func recipeDetails(details model.RecipeDetails) (model.RecipeDetails, error) {
+	steps, err := instructionSteps(details.Instructions)
+	if err != nil {
+		return details, err
+	}
+	details.Instructions = steps
+	if err := checkMinutes("PrepMinutes", details.PrepMinutes); err != nil {
+		return details, err
+	}
+	if err := checkMinutes("CookMinutes", details.CookMinutes); err != nil {
+		return details, err
+	}
+	if details.Difficulty, err = difficulty(details.Difficulty); err != nil {
+		return details, err
+	}
+	if details.SourceURL, err = sourceURL(details.SourceURL); err != nil {
+		return details, err
+	}
+	if details.Notes, err = notes(details.Notes); err != nil {
+		return details, err
+	}
+	return details, nil
+}
+
+// recipeDetailsUpdate validates and normalizes, in place, the details given
+// in a partial recipe update, as recipeDetails does for a new recipe.
+func recipeDetailsUpdate(update *model.RecipeUpdate) error {
+	if update.Instructions != nil {
+		steps, err := instructionSteps(*update.Instructions)
+		if err != nil {
+			return err
+		}
+		update.Instructions = &steps
+	}
+	if update.PrepMinutes != nil {
+		if err := checkMinutes("PrepMinutes", *update.PrepMinutes); err != nil {
+			return err
+		}
+	}
+	if update.CookMinutes != nil {
+		if err := checkMinutes("CookMinutes", *update.CookMinutes); err != nil {
+			return err
+		}
+	}
+	for _, field := range []struct {
+		value     *string
+		normalize func(string) (string, error)
+	}{
+		{update.Difficulty, difficulty},
+		{update.SourceURL, sourceURL},
+		{update.Notes, notes},
+	} {
+		if field.value == nil {
+			continue
+		}
+		normalized, err := field.normalize(*field.value)
+		if err != nil {
+			return err
+		}
+		*field.value = normalized
+	}
+	return nil
+}
+
+// instructionSteps trims the steps of a method and rejects blank or overlong
+// ones. A nil slice stays nil.
+func instructionSteps(steps []string) ([]string, error) {
+	if len(steps) > maxInstructionSteps {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("A recipe can have at most %d instruction steps", maxInstructionSteps))
+	}
+	if steps == nil {
+		return nil, nil
+	}
+	trimmed := make([]string, 0, len(steps))
+	for i, step := range steps {
+		step = strings.TrimSpace(step)
+		if step == "" {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Instruction step %d is empty", i+1))
+		}
+		if utf8.RuneCountInString(step) > maxStepLength {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Instruction step %d is longer than %d characters", i+1, maxStepLength))
+		}
+		trimmed = append(trimmed, step)
+	}
+	return trimmed, nil
+}
+
+func checkMinutes(field string, minutes int) error {
+	if minutes < 0 || minutes > maxRecipeMinutes {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s must be between 0 and %d", field, maxRecipeMinutes))
+	}
+	return nil
+}
+
+// difficulty returns the lowercased difficulty, which must be one of
+// model.Difficulties or empty.
+func difficulty(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value != "" && !slices.Contains(model.Difficulties, value) {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Difficulty must be one of "+strings.Join(model.Difficulties, ", "))
+	}
+	return value, nil
+}
+
+// sourceURL accepts an absolute http or https URL, or empty.
+func sourceURL(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "SourceURL must be an absolute http or https URL")
+	}
+	return value, nil
+}
+
+func notes(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if utf8.RuneCountInString(value) > maxNotesLength {
+		return "", echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Notes must be at most %d characters", maxNotesLength))
+	}
+	return value, nil
+}
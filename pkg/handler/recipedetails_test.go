@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/store"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestRecipeDetailsRejected checks that POST /recipes and PUT /recipes/:id
+// reject invalid details with a 400 naming the field, like the other field
+// checks, and answer 422 only for references to unknown ingredients.
+func TestRecipeDetailsRejected(t *testing.T) {
+	s := store.NewMemoryStore()
+	flour, pancakes := seedPancakes(t, s)
+	e := newTestServer(t, s)
+	seeded, err := s.Recipes().FindByID(context.Background(), pancakes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		details map[string]any
+		message string
+	}{
+		{"too many steps", map[string]any{"Instructions": make([]string, maxInstructionSteps+1)}, "at most 100 instruction steps"},
+		{"blank step", map[string]any{"Instructions": []string{"Mix", " \t"}}, "Instruction step 2 is empty"},
+		{"overlong step", map[string]any{"Instructions": []string{strings.Repeat("é", maxStepLength+1)}}, "Instruction step 1 is longer"},
+		{"negative prep time", map[string]any{"PrepMinutes": -1}, "PrepMinutes must be between"},
+		{"cook time over a week", map[string]any{"CookMinutes": maxRecipeMinutes + 1}, "CookMinutes must be between"},
+		{"unknown difficulty", map[string]any{"Difficulty": "impossible"}, "Difficulty must be one of"},
+		{"relative source URL", map[string]any{"SourceURL": "example.com/pancakes"}, "SourceURL must be"},
+		{"non-http source URL", map[string]any{"SourceURL": "ftp://example.com/pancakes"}, "SourceURL must be"},
+		{"overlong notes", map[string]any{"Notes": strings.Repeat("a", maxNotesLength+1)}, "Notes must be at most"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recipe := map[string]any{
+				"Name":        "crepes",
+				"Ingredients": []map[string]any{{"ObjectID": flour.Hex(), "Quantity": 100, "Unit": "g"}},
+			}
+			for field, value := range tt.details {
+				recipe[field] = value
+			}
+			rec := serve(e, http.MethodPost, "/recipes", []map[string]any{recipe})
+			expectStatus(t, rec, http.StatusBadRequest)
+			if !strings.Contains(rec.Body.String(), tt.message) {
+				t.Errorf("POST body %s does not mention %q", rec.Body.String(), tt.message)
+			}
+
+			rec = serve(e, http.MethodPut, "/recipes/"+pancakes.Hex(), tt.details, "If-Match", "*")
+			expectStatus(t, rec, http.StatusBadRequest)
+			if !strings.Contains(rec.Body.String(), tt.message) {
+				t.Errorf("PUT body %s does not mention %q", rec.Body.String(), tt.message)
+			}
+		})
+	}
+
+	// None of the rejected writes reached the store.
+	recipes, err := s.Recipes().List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recipes) != 1 || recipes[0].Version != seeded.Version {
+		t.Errorf("recipes after the rejected writes: %+v", recipes)
+	}
+
+	t.Run("unknown ingredient", func(t *testing.T) {
+		unknown := primitive.NewObjectID().Hex()
+		recipe := map[string]any{
+			"Name":        "crepes",
+			"Ingredients": []map[string]any{{"ObjectID": unknown, "Quantity": 100, "Unit": "g"}},
+			"Difficulty":  "Easy",
+		}
+		rec := serve(e, http.MethodPost, "/recipes", []map[string]any{recipe})
+		expectStatus(t, rec, http.StatusUnprocessableEntity)
+		if !strings.Contains(rec.Body.String(), unknown) {
+			t.Errorf("POST body %s does not list %s", rec.Body.String(), unknown)
+		}
+
+		update := map[string]any{"Ingredients": recipe["Ingredients"]}
+		rec = serve(e, http.MethodPut, "/recipes/"+pancakes.Hex(), update, "If-Match", "*")
+		expectStatus(t, rec, http.StatusUnprocessableEntity)
+		if !strings.Contains(rec.Body.String(), unknown) {
+			t.Errorf("PUT body %s does not list %s", rec.Body.String(), unknown)
+		}
+	})
+
+	t.Run("valid details are normalized", func(t *testing.T) {
+		rec := serve(e, http.MethodPut, "/recipes/"+pancakes.Hex(), map[string]any{
+			"Instructions": []string{"  Whisk  ", "Fry"},
+			"Difficulty":   " Easy ",
+			"SourceURL":    " https://example.com/pancakes ",
+		}, "If-Match", "*")
+		expectStatus(t, rec, http.StatusOK)
+		var body struct{ Recipe model.Recipe }
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		details := body.Recipe.RecipeDetails
+		if strings.Join(details.Instructions, "|") != "Whisk|Fry" || details.Difficulty != model.DifficultyEasy || details.SourceURL != "https://example.com/pancakes" {
+			t.Errorf("details = %+v", details)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/search"
+	"dynamicrecipes/pkg/store"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+)
+
+// searchIndex backs GET /search. It is filled from the store when the routes
+// are set up and kept current by the handlers that write recipes and
+// ingredients, so it works the same with every backend.
+var searchIndex = search.New()
+
+// ingredientNames backs GET /ingredients/suggest and is maintained alongside
+// searchIndex.
+var ingredientNames = search.NewSuggester()
+
+// loadSearchIndex indexes every stored ingredient and recipe.
+func loadSearchIndex(ctx context.Context, s store.Store) error {
+	ingredients, err := s.Ingredients().List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, ingredient := range ingredients {
+		indexIngredient(ingredient)
+	}
+
+	recipes, err := s.Recipes().List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, recipe := range recipes {
+		searchIndex.Put(search.RecipeDocument(recipe))
+	}
+	return nil
+}
+
+// indexIngredient makes a new or updated ingredient searchable.
+func indexIngredient(ingredient model.Ingredient) {
+	searchIndex.Put(search.IngredientDocument(ingredient))
+	ingredientNames.Put(ingredient.ObjectID.Hex(), ingredient.Name)
+}
+
+// unindexIngredient drops a deleted ingredient from search.
+func unindexIngredient(id string) {
+	searchIndex.Remove(search.KindIngredient, id)
+	ingredientNames.Remove(id)
+}
+
+// handleSearch serves GET /search?q=&kind=&limit=, a typo-tolerant ranked
+// search over recipe and ingredient names.
+func handleSearch(c echo.Context) error {
+	query := c.QueryParam("q")
+	if query == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "q is required")
+	}
+
+	kind := search.Kind(c.QueryParam("kind"))
+	switch kind {
+	case "", search.KindRecipe, search.KindIngredient:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "kind must be recipe or ingredient")
+	}
+
+	limit, err := limitParam(c, defaultSearchLimit, maxSearchLimit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, searchIndex.Search(query, kind, limit))
+}
+
+// handleSuggest serves GET /ingredients/suggest?q=&limit=, completing
+// ingredient names as they are typed.
+func handleSuggest(c echo.Context) error {
+	query := c.QueryParam("q")
+	if query == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "q is required")
+	}
+
+	limit, err := limitParam(c, defaultSuggestLimit, maxSuggestLimit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, ingredientNames.Suggest(query, limit))
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"dynamicrecipes/pkg/search"
+	"dynamicrecipes/pkg/store"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestSearchFollowsWrites checks that renamed and deleted ingredients and
+// recipes drop out of the suggestions and the search. The indexes are shared
+// by every server in the process, so names carry a unique word.
+func TestSearchFollowsWrites(t *testing.T) {
+	s := store.NewMemoryStore()
+	flour, pancakes := seedPancakes(t, s)
+	e := newTestServer(t, s)
+
+	suggest := func(query string) []string {
+		t.Helper()
+		rec := serve(e, http.MethodGet, "/ingredients/suggest?q="+url.QueryEscape(query), nil)
+		expectStatus(t, rec, http.StatusOK)
+		var suggestions []search.Suggestion
+		if err := json.Unmarshal(rec.Body.Bytes(), &suggestions); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, suggestion := range suggestions {
+			ids = append(ids, suggestion.ID)
+		}
+		return ids
+	}
+	searchRecipes := func(query string) []string {
+		t.Helper()
+		rec := serve(e, http.MethodGet, "/search?kind=recipe&q="+url.QueryEscape(query), nil)
+		expectStatus(t, rec, http.StatusOK)
+		var results []search.Result
+		if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, result := range results {
+			ids = append(ids, result.ID)
+		}
+		return ids
+	}
+
+	// seedPancakes names the flour "flour <unique word>".
+	seeded, err := s.Ingredients().FindByID(context.Background(), flour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldWord := strings.Fields(seeded.Name)[1]
+	if got := suggest(oldWord); !slices.Equal(got, []string{flour.Hex()}) {
+		t.Fatalf("suggestions for the seeded flour: %q", got)
+	}
+	newWord := primitive.NewObjectID().Hex()
+	rec := serve(e, http.MethodPut, "/ingredients/"+flour.Hex(), map[string]any{"name": "rye " + newWord}, "If-Match", "*")
+	expectStatus(t, rec, http.StatusOK)
+	if got := suggest(oldWord); len(got) != 0 {
+		t.Errorf("the old name is still suggested after renaming: %q", got)
+	}
+	if got := suggest(newWord); !slices.Equal(got, []string{flour.Hex()}) {
+		t.Errorf("suggestions for the new name: %q", got)
+	}
+
+	recipeWord := primitive.NewObjectID().Hex()
+	rec = serve(e, http.MethodPut, "/recipes/"+pancakes.Hex(), map[string]any{"Name": "waffles " + recipeWord}, "If-Match", "*")
+	expectStatus(t, rec, http.StatusOK)
+	if got := searchRecipes("pancakes"); slices.Contains(got, pancakes.Hex()) {
+		t.Errorf("the recipe is still found by its old name after renaming: %q", got)
+	}
+	if got := searchRecipes(recipeWord); !slices.Equal(got, []string{pancakes.Hex()}) {
+		t.Errorf("search for the new recipe name: %q", got)
+	}
+
+	rec = serve(e, http.MethodDelete, "/recipes/"+pancakes.Hex(), nil, "If-Match", "*")
+	expectStatus(t, rec, http.StatusOK)
+	if got := searchRecipes(recipeWord); len(got) != 0 {
+		t.Errorf("the recipe is still found after deleting it: %q", got)
+	}
+
+	rec = serve(e, http.MethodDelete, "/ingredients/"+url.PathEscape("rye "+newWord), nil, "If-Match", "*")
+	expectStatus(t, rec, http.StatusOK)
+	if got := suggest(newWord); len(got) != 0 {
+		t.Errorf("the ingredient is still suggested after deleting it: %q", got)
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/repository"
+	"dynamicrecipes/pkg/units"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// shoppingListItem is the request body form of a shopping list item. Either
+// IngredientID or Name must be given; items of an ingredient default to its
+// name and category.
+type shoppingListItem struct {
+	IngredientID string  `json:"IngredientID"`
+	Name         string  `json:"Name"`
+	Category     string  `json:"Category"`
+	Quantity     float64 `json:"Quantity"`
+	Unit         string  `json:"Unit"`
+	Checked      bool    `json:"Checked"`
+}
+
+// shoppingListItems validates request items and converts them to the model.
+func shoppingListItems(items []shoppingListItem) ([]model.ShoppingListItem, error) {
+	docs := make([]model.ShoppingListItem, 0, len(items))
+	for _, item := range items {
+		doc := model.ShoppingListItem{
+			IngredientID: strings.TrimSpace(item.IngredientID),
+			Name:         strings.TrimSpace(item.Name),
+			Category:     strings.ToLower(strings.TrimSpace(item.Category)),
+			Quantity:     item.Quantity,
+			Unit:         listUnit(item.Unit),
+			Checked:      item.Checked,
+		}
+		if doc.IngredientID == "" && doc.Name == "" {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Every item needs a Name or an IngredientID")
+		}
+		if doc.Quantity < 0 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Quantity must not be negative")
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// listUnit returns the canonical symbol of a known unit. Unlike recipes and
+// pantries, shopping lists also take units the converter does not know, such
+// as "pack", as they are given.
+func listUnit(unit string) string {
+	if u, ok := units.Lookup(unit); ok {
+		return u.Symbol
+	}
+	return strings.TrimSpace(unit)
+}
+
+// shoppingListError maps the errors of ShoppingListRepository that are the
+// client's fault to HTTP errors, and any other error to a 500 with message.
+func shoppingListError(err error, message string) error {
+	switch {
+	case errors.Is(err, primitive.ErrInvalidHex):
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid shopping list, item or ingredient ID")
+	case errors.Is(err, repository.ErrUnknownIngredient):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, repository.ErrNotListOwner):
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
+
+// publishShoppingList pushes a change to list over /ws to its owner and
+// members, and to any other users given, e.g. members just removed from it.
+func publishShoppingList(eventType string, list *model.ShoppingList, itemID string, others ...string) {
+	users := append([]string{list.OwnerID}, list.Members...)
+	event := ShoppingListEvent{Type: eventType, ListID: list.ObjectID.Hex(), ItemID: itemID, Version: list.Version}
+	if eventType != ShoppingListDeleted {
+		event.List = list
+	}
+	notifyUsers(append(users, others...), event)
+}
@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"dynamicrecipes/pkg/model"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"sync"
 
@@ -93,19 +95,82 @@ func HandleWebSocketConnection(c echo.Context) error {
 	return nil
 }
 
-// StartBroadcasting listens to the broadcast channel and sends the message to all clients
+// ShoppingListEvent is pushed to the owner and members of a shopping list
+// whenever it changes, on every connection they have open. Unlike chat
+// messages it has a type, and it is not kept in the message history.
+type ShoppingListEvent struct {
+	Type   string `json:"type"`
+	ListID string `json:"listId"`
+	// ItemID is set for changes to a single item.
+	ItemID  string `json:"itemId,omitempty"`
+	Version int64  `json:"version"`
+	// List is the list as changed; it is nil once the list is deleted.
+	List *model.ShoppingList `json:"list,omitempty"`
+}
+
+// Shopping list event types.
+const (
+	ShoppingListCreated     = "shoppingList.created"
+	ShoppingListUpdated     = "shoppingList.updated"
+	ShoppingListDeleted     = "shoppingList.deleted"
+	ShoppingListItemsAdded  = "shoppingList.itemsAdded"
+	ShoppingListItemUpdated = "shoppingList.itemUpdated"
+	ShoppingListItemRemoved = "shoppingList.itemRemoved"
+)
+
+// userMessage is a message for the connections of the given users only.
+type userMessage struct {
+	users   map[string]bool
+	payload interface{}
+}
+
+// notifications carries messages for some users to StartBroadcasting. It is
+// buffered so that a request does not wait on a slow connection.
+var notifications = make(chan userMessage, 64)
+
+// notifyUsers queues payload for every connection of the given users. It never
+// blocks: when the queue is full, because connections are slow or nothing is
+// broadcasting, the message is dropped and logged. Clients recover from a
+// missed event by fetching the list again.
+func notifyUsers(users []string, payload interface{}) {
+	msg := userMessage{users: make(map[string]bool, len(users)), payload: payload}
+	for _, userId := range users {
+		msg.users[userId] = true
+	}
+	select {
+	case notifications <- msg:
+	default:
+		log.Printf("Notification queue is full, dropping a message for %d user(s)", len(users))
+	}
+}
+
+// StartBroadcasting listens to the broadcast channel and sends the message to all clients,
+// and to the notifications channel and sends the message to the clients of its users
 func StartBroadcasting() {
 	for {
-		msg := <-broadcast
+		var (
+			payload interface{}
+			users   map[string]bool // nil means every client
+		)
+		select {
+		case msg := <-broadcast:
+			payload = msg
+		case msg := <-notifications:
+			payload, users = msg.payload, msg.users
+		}
+
 		mutex.Lock()
-		jsonData, err := json.Marshal(msg)
+		jsonData, err := json.Marshal(payload)
 		if err != nil {
 			// Handle error in JSON marshaling
 			mutex.Unlock()
 			continue // Skip sending this message
 		}
 
-		for client := range clients {
+		for client, userId := range clients {
+			if users != nil && !users[userId] {
+				continue
+			}
 			err := client.WriteMessage(websocket.TextMessage, jsonData)
 			if err != nil {
 				client.Close()
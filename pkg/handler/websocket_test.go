@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotifyUsersDropsWhenQueueIsFull(t *testing.T) {
+	// Nothing is broadcasting in tests, so the queue fills up.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < cap(notifications)+10; i++ {
+			notifyUsers([]string{"alice"}, i)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("notifyUsers blocked on a full queue")
+	}
+
+	if len(notifications) != cap(notifications) {
+		t.Errorf("%d notifications queued, want %d", len(notifications), cap(notifications))
+	}
+	for len(notifications) > 0 {
+		<-notifications
+	}
+}
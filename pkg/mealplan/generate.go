@@ -0,0 +1,260 @@
+package mealplan
+
+import (
+	"dynamicrecipes/pkg/model"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// slotShares is the part of the daily calorie target each slot aims for,
+// relative to the other slots planned.
+var slotShares = map[string]float64{
+	model.SlotBreakfast: 0.25,
+	model.SlotLunch:     0.35,
+	model.SlotDinner:    0.40,
+	model.SlotSnack:     0.10,
+}
+
+// DefaultSlots are the slots planned when none are given.
+var DefaultSlots = []string{model.SlotBreakfast, model.SlotLunch, model.SlotDinner}
+
+const (
+	// DefaultMaxServings caps the servings of one recipe at one meal.
+	DefaultMaxServings = 3
+	// choices is the most recipes one is drawn from at random per meal.
+	choices = 3
+	// tolerance is how much further from its calorie share than the
+	// closest recipe another may be and still be drawn, as a fraction of
+	// the share.
+	tolerance = 0.05
+)
+
+// Options constrains Generate.
+type Options struct {
+	// Target is the daily calorie target in kcal.
+	Target float64
+	// Slots are the meals of every day; DefaultSlots when empty.
+	Slots []string
+	// ExcludedIngredients are hex IDs of ingredients no chosen recipe may
+	// contain.
+	ExcludedIngredients []string
+	// MaxRepeatsPerWeek caps how often a recipe is chosen per Monday to
+	// Sunday week; 0 means no cap.
+	MaxRepeatsPerWeek int
+	// MaxServings caps the servings of a recipe at one meal;
+	// DefaultMaxServings when 0.
+	MaxServings int
+	// Seed drives the choice among recipes that fit equally well.
+	Seed int64
+}
+
+// candidate is a recipe Generate may choose, with its calories per serving.
+type candidate struct {
+	recipe   model.Recipe
+	calories float64
+}
+
+// candidates returns the recipes that meet the constraints of opts, in ID
+// order so that the result does not depend on the order recipes are given in.
+// Recipes without calorie data cannot help reach a target and are left out.
+func candidates(recipes []model.Recipe, opts Options) []candidate {
+	excluded := make(map[string]bool, len(opts.ExcludedIngredients))
+	for _, id := range opts.ExcludedIngredients {
+		excluded[id] = true
+	}
+
+	var result []candidate
+recipes:
+	for _, recipe := range recipes {
+		for _, line := range recipe.Ingredients {
+			if excluded[line.ObjectID.Hex()] {
+				continue recipes
+			}
+		}
+		if recipe.Nutrition.PerServing.Calories <= 0 {
+			continue
+		}
+		result = append(result, candidate{recipe: recipe, calories: recipe.Nutrition.PerServing.Calories})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].recipe.ObjectID.Hex() < result[j].recipe.ObjectID.Hex()
+	})
+	return result
+}
+
+// fit returns the number of servings of c, up to maxServings, whose calories
+// come closest to share, and how far off they are.
+func (c candidate) fit(share float64, maxServings int) (servings int, off float64) {
+	servings = int(math.Round(share / c.calories))
+	servings = min(max(servings, 1), maxServings)
+	return servings, math.Abs(float64(servings)*c.calories - share)
+}
+
+// Generate proposes a meal plan for the given number of days from from that
+// comes close to opts.Target every day, choosing from recipes. Each day's
+// target is split over its slots; every meal aims for its slot's share of
+// what is left of the day, so later meals make up for earlier ones. Among the
+// recipes whose servings come closest to that share, one is drawn with a
+// random source seeded by opts.Seed. A recipe is not chosen twice a day while
+// others are left, nor more often than opts.MaxRepeatsPerWeek in a week.
+func Generate(recipes []model.Recipe, from time.Time, days int, opts Options) model.GeneratedMealPlan {
+	slots := append([]string{}, opts.Slots...)
+	if len(slots) == 0 {
+		slots = DefaultSlots
+	}
+	sort.SliceStable(slots, func(i, j int) bool { return slotRank(slots[i]) < slotRank(slots[j]) })
+	maxServings := opts.MaxServings
+	if maxServings <= 0 {
+		maxServings = DefaultMaxServings
+	}
+	pool := candidates(recipes, opts)
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	from = from.UTC().Truncate(Day)
+	to := from.AddDate(0, 0, days)
+	var entries []model.MealPlanEntry
+	// unfilled lists the slots of each day no recipe was left for.
+	unfilled := make(map[string][]string)
+	// uses counts the choices of each recipe per week, by Monday.
+	uses := make(map[time.Time]map[string]int)
+
+	for day := from; day.Before(to); day = day.Add(Day) {
+		week, _ := Week(day)
+		if uses[week] == nil {
+			uses[week] = make(map[string]int)
+		}
+		usedToday := make(map[string]bool)
+		remaining := opts.Target
+
+		for i, slot := range slots {
+			var shares float64
+			for _, later := range slots[i:] {
+				shares += slotShares[later]
+			}
+			share := remaining * slotShares[slot] / shares
+
+			choice, servings, ok := choose(pool, share, maxServings, rng, func(c candidate) (allowed, preferred bool) {
+				id := c.recipe.ObjectID.Hex()
+				if opts.MaxRepeatsPerWeek > 0 && uses[week][id] >= opts.MaxRepeatsPerWeek {
+					return false, false
+				}
+				return true, !usedToday[id]
+			})
+			date := day.Format(time.DateOnly)
+			if !ok {
+				unfilled[date] = append(unfilled[date], slot)
+				continue
+			}
+
+			id := choice.recipe.ObjectID.Hex()
+			uses[week][id]++
+			usedToday[id] = true
+			remaining -= float64(servings) * choice.calories
+			entries = append(entries, model.MealPlanEntry{Date: day, Slot: slot, RecipeID: id, Servings: servings})
+		}
+	}
+
+	byID := make(map[string]model.Recipe, len(pool))
+	for _, c := range pool {
+		byID[c.recipe.ObjectID.Hex()] = c.recipe
+	}
+	plan := model.GeneratedMealPlan{
+		MealPlan:     Build(from, to, entries, byID),
+		Seed:         opts.Seed,
+		Target:       opts.Target,
+		Candidates:   len(pool),
+		Explanations: make([]model.DayExplanation, 0, days),
+	}
+	for _, day := range plan.Days {
+		plan.Explanations = append(plan.Explanations, explain(day, opts.Target, unfilled[day.Date]))
+	}
+	return plan
+}
+
+// choose draws one of the allowed candidates whose servings come closest to
+// share, favouring preferred ones, and returns it with its servings. ok is
+// false if no candidate is allowed.
+func choose(pool []candidate, share float64, maxServings int, rng *rand.Rand, allow func(candidate) (allowed, preferred bool)) (c candidate, servings int, ok bool) {
+	type option struct {
+		candidate
+		servings int
+		off      float64
+	}
+	var preferred, others []option
+	for _, c := range pool {
+		allowed, isPreferred := allow(c)
+		if !allowed {
+			continue
+		}
+		servings, off := c.fit(share, maxServings)
+		if isPreferred {
+			preferred = append(preferred, option{c, servings, off})
+		} else {
+			others = append(others, option{c, servings, off})
+		}
+	}
+	options := preferred
+	if len(options) == 0 {
+		options = others
+	}
+	if len(options) == 0 {
+		return candidate{}, 0, false
+	}
+
+	// Stable, so that equally good options stay in ID order.
+	sort.SliceStable(options, func(i, j int) bool { return options[i].off < options[j].off })
+	n := 1
+	for n < len(options) && n < choices && options[n].off <= options[0].off+tolerance*share {
+		n++
+	}
+	picked := options[rng.Intn(n)]
+	return picked.candidate, picked.servings, true
+}
+
+// explain accounts for the calories of a generated day.
+func explain(day model.MealPlanDay, target float64, unfilled []string) model.DayExplanation {
+	var text strings.Builder
+	for i, meal := range day.Meals {
+		if i > 0 {
+			text.WriteString(" + ")
+		}
+		fmt.Fprintf(&text, "%s: %s ×%d (%g kcal)", meal.Slot, meal.RecipeName, meal.Servings, meal.Nutrition.Calories)
+	}
+	if len(day.Meals) == 0 {
+		text.WriteString("No meals")
+	}
+
+	calories := day.Total.Calories
+	difference := math.Round((calories-target)*10) / 10
+	fmt.Fprintf(&text, " = %g kcal", calories)
+	switch {
+	case difference == 0:
+		fmt.Fprintf(&text, ", exactly the %g kcal target.", target)
+	case target > 0:
+		direction := "over"
+		if difference < 0 {
+			direction = "under"
+		}
+		fmt.Fprintf(&text, ", %g kcal (%.1f%%) %s the %g kcal target.", math.Abs(difference), math.Abs(difference)/target*100, direction, target)
+	default:
+		text.WriteString(".")
+	}
+	if len(unfilled) > 0 {
+		fmt.Fprintf(&text, " No recipe was left within the constraints for %s.", strings.Join(unfilled, ", "))
+	}
+	if day.Incomplete {
+		text.WriteString(" Some ingredient amounts could not be converted to grams, so the total is too low.")
+	}
+
+	return model.DayExplanation{
+		Date:       day.Date,
+		Calories:   calories,
+		Target:     target,
+		Difference: difference,
+		Text:       text.String(),
+	}
+}
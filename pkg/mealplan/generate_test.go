@@ -0,0 +1,74 @@
+package mealplan
+
+import (
+	"dynamicrecipes/pkg/model"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// menu returns one-serving recipes of 200 to 800 kcal in steps of 50, so that
+// any share of a day's target in that range can be met within 25 kcal.
+func menu() []model.Recipe {
+	var recipes []model.Recipe
+	for calories := 200.0; calories <= 800; calories += 50 {
+		recipes = append(recipes, model.Recipe{
+			ObjectID:  primitive.NewObjectID(),
+			Name:      fmt.Sprintf("%g kcal", calories),
+			Servings:  1,
+			Nutrition: model.Nutrition{PerServing: model.Macros{Calories: calories}},
+		})
+	}
+	return recipes
+}
+
+func TestGenerateIsReproducible(t *testing.T) {
+	recipes := menu()
+	from := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	opts := Options{Target: 2000, Seed: 42}
+
+	plan := Generate(recipes, from, 14, opts)
+	if again := Generate(recipes, from, 14, opts); !reflect.DeepEqual(again, plan) {
+		t.Errorf("a second plan with the same seed differs:\n%+v\n%+v", again, plan)
+	}
+	reversed := slices.Clone(recipes)
+	slices.Reverse(reversed)
+	if shuffled := Generate(reversed, from, 14, opts); !reflect.DeepEqual(shuffled, plan) {
+		t.Error("the plan depends on the order recipes are given in")
+	}
+
+	opts.Seed = 43
+	if other := Generate(recipes, from, 14, opts); reflect.DeepEqual(other.Days, plan.Days) {
+		t.Error("another seed gives the same plan")
+	}
+}
+
+func TestGenerateStaysWithinTolerance(t *testing.T) {
+	from := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	for _, target := range []float64{1500, 2000, 2500} {
+		for seed := int64(0); seed < 5; seed++ {
+			opts := Options{Target: target, Seed: seed}
+			plan := Generate(menu(), from, 7, opts)
+
+			if len(plan.Days) != 7 || len(plan.Explanations) != 7 {
+				t.Fatalf("target %g, seed %d: %d days and %d explanations, want 7", target, seed, len(plan.Days), len(plan.Explanations))
+			}
+			for i, day := range plan.Days {
+				if len(day.Meals) != len(DefaultSlots) {
+					t.Errorf("target %g, seed %d, %s: %d meals, want %d", target, seed, day.Date, len(day.Meals), len(DefaultSlots))
+				}
+				if off := math.Abs(day.Total.Calories - target); off > tolerance*target {
+					t.Errorf("target %g, seed %d, %s: %g kcal is %g off, more than %g", target, seed, day.Date, day.Total.Calories, off, tolerance*target)
+				}
+				if explained := plan.Explanations[i]; explained.Calories != day.Total.Calories || explained.Target != target {
+					t.Errorf("target %g, seed %d, %s: explained as %+v", target, seed, day.Date, explained)
+				}
+			}
+		}
+	}
+}
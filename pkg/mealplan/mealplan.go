@@ -0,0 +1,97 @@
+package mealplan
+
+import (
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/nutrition"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Day is the length of a meal plan day. Plans are kept in UTC, so days do
+// not vary in length.
+const Day = 24 * time.Hour
+
+// Week returns the Monday to Sunday week containing date, as the first day
+// and the day after the last.
+func Week(date time.Time) (from, to time.Time) {
+	date = date.UTC().Truncate(Day)
+	// time.Weekday counts from Sunday; weeks here start on Monday.
+	offset := (int(date.Weekday()) + 6) % 7
+	from = date.AddDate(0, 0, -offset)
+	return from, from.AddDate(0, 0, 7)
+}
+
+// Month returns the calendar month containing date, as the first day and the
+// first day of the next month.
+func Month(date time.Time) (from, to time.Time) {
+	date = date.UTC()
+	from = time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(0, 1, 0)
+}
+
+// slotRank orders meals within a day by model.MealSlots.
+func slotRank(slot string) int {
+	if i := slices.Index(model.MealSlots, slot); i >= 0 {
+		return i
+	}
+	return len(model.MealSlots)
+}
+
+// Build lays out the given entries over the days from from up to but excluding
+// to, days without meals included, and totals the nutrition of every day and
+// of the whole range from the recipes, keyed by hex ID. Entries whose recipe
+// is missing from recipes are listed but count for nothing.
+func Build(from, to time.Time, entries []model.MealPlanEntry, recipes map[string]model.Recipe) model.MealPlan {
+	plan := model.MealPlan{
+		From: from.Format(time.DateOnly),
+		To:   to.Add(-Day).Format(time.DateOnly),
+		Days: make([]model.MealPlanDay, 0),
+	}
+	index := make(map[string]int)
+	for day := from; day.Before(to); day = day.Add(Day) {
+		date := day.Format(time.DateOnly)
+		index[date] = len(plan.Days)
+		plan.Days = append(plan.Days, model.MealPlanDay{Date: date, Meals: make([]model.PlannedMeal, 0)})
+	}
+
+	for _, entry := range entries {
+		i, ok := index[entry.Date.UTC().Format(time.DateOnly)]
+		if !ok {
+			continue
+		}
+		day := &plan.Days[i]
+		meal := model.PlannedMeal{
+			EntryID:  entry.ObjectID,
+			Slot:     entry.Slot,
+			RecipeID: entry.RecipeID,
+			Servings: entry.Servings,
+		}
+		if recipe, ok := recipes[entry.RecipeID]; ok {
+			meal.RecipeName = recipe.Name
+			meal.Nutrition = nutrition.ForServings(recipe.Nutrition, entry.Servings)
+			if len(recipe.Nutrition.Unmeasured) > 0 {
+				day.Incomplete = true
+			}
+		} else {
+			meal.RecipeMissing = true
+		}
+		day.Meals = append(day.Meals, meal)
+	}
+
+	totals := make([]model.Macros, 0, len(plan.Days))
+	for i := range plan.Days {
+		day := &plan.Days[i]
+		sort.SliceStable(day.Meals, func(a, b int) bool {
+			return slotRank(day.Meals[a].Slot) < slotRank(day.Meals[b].Slot)
+		})
+		meals := make([]model.Macros, 0, len(day.Meals))
+		for _, meal := range day.Meals {
+			meals = append(meals, meal.Nutrition)
+		}
+		day.Total = nutrition.Sum(meals...)
+		totals = append(totals, day.Total)
+	}
+	plan.Total = nutrition.Sum(totals...)
+	return plan
+}
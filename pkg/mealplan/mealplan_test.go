@@ -0,0 +1,131 @@
+package mealplan
+
+import (
+	"dynamicrecipes/pkg/model"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBuild(t *testing.T) {
+	oats := model.Recipe{ObjectID: primitive.NewObjectID(), Name: "oats", Servings: 1,
+		Nutrition: model.Nutrition{PerServing: model.Macros{Calories: 300, Protein: 10}}}
+	// The soup has an amount that could not be converted, so its calories
+	// are too low.
+	soup := model.Recipe{ObjectID: primitive.NewObjectID(), Name: "soup", Servings: 4,
+		Nutrition: model.Nutrition{PerServing: model.Macros{Calories: 200, Protein: 5}, Unmeasured: []string{"stock"}}}
+	recipes := map[string]model.Recipe{oats.ObjectID.Hex(): oats, soup.ObjectID.Hex(): soup}
+
+	day := func(d int, hour int) time.Time { return time.Date(2026, 3, d, hour, 0, 0, 0, time.UTC) }
+	entry := func(date time.Time, slot string, recipe model.Recipe, servings int) model.MealPlanEntry {
+		return model.MealPlanEntry{ObjectID: primitive.NewObjectID(), Date: date, Slot: slot, RecipeID: recipe.ObjectID.Hex(), Servings: servings}
+	}
+	deleted := model.Recipe{ObjectID: primitive.NewObjectID()}
+	evening := time.FixedZone("UTC-2", -2*60*60)
+
+	tests := []struct {
+		name    string
+		entries []model.MealPlanEntry
+		want    []string
+		total   model.Macros
+	}{
+		{
+			name: "no entries",
+			want: []string{"2026-03-02: = 0", "2026-03-03: = 0", "2026-03-04: = 0"},
+		},
+		{
+			name: "grouped by day and ordered by slot",
+			entries: []model.MealPlanEntry{
+				entry(day(2, 0), model.SlotDinner, oats, 1),
+				entry(day(3, 12), model.SlotSnack, oats, 1),
+				entry(day(2, 0), model.SlotBreakfast, oats, 2),
+				entry(day(3, 0), model.SlotLunch, oats, 1),
+				entry(day(2, 0), model.SlotBreakfast, oats, 1),
+				// Dates are taken in UTC: this is early on the 3rd.
+				entry(time.Date(2026, 3, 2, 23, 0, 0, 0, evening), model.SlotBreakfast, oats, 1),
+			},
+			want: []string{
+				"2026-03-02: breakfast oats x2 600, breakfast oats x1 300, dinner oats x1 300 = 1200",
+				"2026-03-03: breakfast oats x1 300, lunch oats x1 300, snack oats x1 300 = 900",
+				"2026-03-04: = 0",
+			},
+			total: model.Macros{Calories: 2100, Protein: 70},
+		},
+		{
+			name: "entries outside the range are left out",
+			entries: []model.MealPlanEntry{
+				entry(day(1, 0), model.SlotDinner, oats, 1),
+				entry(day(4, 0), model.SlotDinner, oats, 1),
+				entry(day(5, 0), model.SlotDinner, oats, 1),
+			},
+			want:  []string{"2026-03-02: = 0", "2026-03-03: = 0", "2026-03-04: dinner oats x1 300 = 300"},
+			total: model.Macros{Calories: 300, Protein: 10},
+		},
+		{
+			name: "unconvertible amounts mark only their day incomplete",
+			entries: []model.MealPlanEntry{
+				entry(day(2, 0), model.SlotLunch, soup, 2),
+				entry(day(2, 0), model.SlotDinner, oats, 1),
+				entry(day(3, 0), model.SlotDinner, oats, 1),
+			},
+			want: []string{
+				"2026-03-02: lunch soup x2 400, dinner oats x1 300 = 700 (incomplete)",
+				"2026-03-03: dinner oats x1 300 = 300",
+				"2026-03-04: = 0",
+			},
+			total: model.Macros{Calories: 1000, Protein: 30},
+		},
+		{
+			name: "deleted recipes count for nothing",
+			entries: []model.MealPlanEntry{
+				entry(day(2, 0), model.SlotLunch, deleted, 2),
+				entry(day(2, 0), model.SlotDinner, oats, 1),
+			},
+			want:  []string{"2026-03-02: lunch (missing) x2 0, dinner oats x1 300 = 300", "2026-03-03: = 0", "2026-03-04: = 0"},
+			total: model.Macros{Calories: 300, Protein: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := Build(day(2, 0), day(5, 0), tt.entries, recipes)
+			if plan.From != "2026-03-02" || plan.To != "2026-03-04" {
+				t.Errorf("plan runs from %s to %s, want 2026-03-02 to 2026-03-04", plan.From, plan.To)
+			}
+
+			var got []string
+			for _, day := range plan.Days {
+				if day.Meals == nil {
+					t.Errorf("%s: Meals is nil rather than empty", day.Date)
+				}
+				line := day.Date + ":"
+				var meals []string
+				for _, meal := range day.Meals {
+					name := meal.RecipeName
+					if meal.RecipeMissing {
+						name = "(missing)"
+					}
+					meals = append(meals, fmt.Sprintf("%s %s x%d %g", meal.Slot, name, meal.Servings, meal.Nutrition.Calories))
+				}
+				if len(meals) > 0 {
+					line += " " + strings.Join(meals, ", ")
+				}
+				line += fmt.Sprintf(" = %g", day.Total.Calories)
+				if day.Incomplete {
+					line += " (incomplete)"
+				}
+				got = append(got, line)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Build() days =\n%q\nwant\n%q", got, tt.want)
+			}
+			if plan.Total != tt.total {
+				t.Errorf("Build() total = %+v, want %+v", plan.Total, tt.total)
+			}
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package model
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"dynamicrecipes/pkg/units"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type IngredientsResult = []Ingredient
 type RecipeResult = []RecipeReturnType
@@ -10,25 +15,436 @@ type Ingredient struct {
 	ObjectID primitive.ObjectID `bson:"_id,omitempty"` // Use `omitempty` to ignore empty values during marshalling and to allow MongoDB to auto-generate the ID.
 	Name     string             `bson:"name"`
 	Calories int                `bson:"calories_per_gram"`
+	// Density and GramsPerPiece let volume and count amounts be converted to
+	// grams. Zero means unknown.
+	Density       float64 `bson:"density_g_per_ml,omitempty"`
+	GramsPerPiece float64 `bson:"grams_per_piece,omitempty"`
+	// Optional macronutrients, in grams per gram of ingredient.
+	Protein float64 `bson:"protein_per_gram,omitempty"`
+	Fat     float64 `bson:"fat_per_gram,omitempty"`
+	Carbs   float64 `bson:"carbs_per_gram,omitempty"`
+	Fiber   float64 `bson:"fiber_per_gram,omitempty"`
+	// Category groups ingredients on shopping lists, e.g. "dairy".
+	Category string `bson:"category,omitempty"`
+	// Version is incremented by every update, starting at 1 on insert.
+	// Documents written before versioning have version 0.
+	Version int64 `bson:"version"`
+}
+
+// Conversion returns the data needed to convert amounts of the ingredient
+// between mass, volume and count.
+func (i Ingredient) Conversion() units.Density {
+	return units.Density{GramsPerML: i.Density, GramsPerPiece: i.GramsPerPiece}
+}
+
+// IngredientUpdate holds a partial update of an ingredient; nil fields are left untouched.
+type IngredientUpdate struct {
+	Name          *string
+	Calories      *int
+	Density       *float64
+	GramsPerPiece *float64
+	Protein       *float64
+	Fat           *float64
+	Carbs         *float64
+	Fiber         *float64
+	Category      *string
+}
+
+// Apply copies the non-nil fields of the update onto ingredient.
+func (u IngredientUpdate) Apply(ingredient *Ingredient) {
+	if u.Name != nil {
+		ingredient.Name = *u.Name
+	}
+	if u.Calories != nil {
+		ingredient.Calories = *u.Calories
+	}
+	if u.Density != nil {
+		ingredient.Density = *u.Density
+	}
+	if u.GramsPerPiece != nil {
+		ingredient.GramsPerPiece = *u.GramsPerPiece
+	}
+	if u.Protein != nil {
+		ingredient.Protein = *u.Protein
+	}
+	if u.Fat != nil {
+		ingredient.Fat = *u.Fat
+	}
+	if u.Carbs != nil {
+		ingredient.Carbs = *u.Carbs
+	}
+	if u.Fiber != nil {
+		ingredient.Fiber = *u.Fiber
+	}
+	if u.Category != nil {
+		ingredient.Category = *u.Category
+	}
 }
 
 // IngredientIDType to match the incoming JSON structure for ingredients.
+// Quantity and Unit describe how much of the ingredient the recipe uses, e.g. 200 "g".
 type IngredientIDType struct {
-	ObjectID string `json:"ObjectID"`
+	ObjectID string  `json:"ObjectID"`
+	Quantity float64 `json:"Quantity" bson:"quantity,omitempty"`
+	Unit     string  `json:"Unit" bson:"unit,omitempty"`
+}
+
+// Recipe difficulties, from least to most demanding.
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+// Difficulties lists the valid recipe difficulties.
+var Difficulties = []string{DifficultyEasy, DifficultyMedium, DifficultyHard}
+
+// RecipeDetails describes how a recipe is made. Every field is optional, and
+// recipes stored before the fields existed read back with all of them empty.
+type RecipeDetails struct {
+	// Instructions are the steps of the method, in order.
+	Instructions []string `json:"Instructions" bson:"instructions,omitempty"`
+	PrepMinutes  int      `json:"PrepMinutes" bson:"prep_minutes,omitempty"`
+	CookMinutes  int      `json:"CookMinutes" bson:"cook_minutes,omitempty"`
+	// Difficulty is one of Difficulties, or empty if not rated.
+	Difficulty string `json:"Difficulty" bson:"difficulty,omitempty"`
+	SourceURL  string `json:"SourceURL" bson:"source_url,omitempty"`
+	Notes      string `json:"Notes" bson:"notes,omitempty"`
 }
 
 type RecipeReturnType struct {
-	Name string             `bson:"name"`
-	ID   []IngredientIDType `bson:"ingredients"`
+	ObjectID      primitive.ObjectID `bson:"_id,omitempty"`
+	Name          string             `bson:"name"`
+	ID            []IngredientIDType `bson:"ingredients"`
+	Servings      int                `bson:"servings,omitempty"`
+	RecipeDetails `bson:",inline"`
+	// CaloriesPerServing is derived from the ingredient lines and kept up to
+	// date by the repository, so that listings can sort and filter by it.
+	CaloriesPerServing int   `bson:"calories_per_serving"`
+	Version            int64 `bson:"version"`
 }
 
 // RecipePostType adjusted to include a slice of IngredientIDType.
 type RecipePostType struct {
-	Name        string             `json:"Name"`
-	Ingredients []IngredientIDType `json:"Ingredients"`
+	Name          string             `json:"Name"`
+	Ingredients   []IngredientIDType `json:"Ingredients"`
+	Servings      int                `json:"Servings" bson:"servings,omitempty"`
+	RecipeDetails `bson:",inline"`
+	// CaloriesPerServing is computed by the repository on insert and cannot
+	// be sent by clients.
+	CaloriesPerServing int `json:"-" bson:"calories_per_serving"`
+	// Version is set by the store on insert and cannot be sent by clients.
+	Version int64 `json:"-" bson:"version"`
+}
+
+// RecipeUpdate holds a partial update of a recipe; nil fields are left untouched.
+type RecipeUpdate struct {
+	Name         *string
+	Ingredients  *[]IngredientIDType
+	Servings     *int
+	Instructions *[]string
+	PrepMinutes  *int
+	CookMinutes  *int
+	Difficulty   *string
+	SourceURL    *string
+	Notes        *string
+	// CaloriesPerServing is set by the repository whenever Ingredients or
+	// Servings change.
+	CaloriesPerServing *int
+}
+
+// Apply copies the non-nil fields of the update onto recipe.
+func (u RecipeUpdate) Apply(recipe *RecipeReturnType) {
+	if u.Name != nil {
+		recipe.Name = *u.Name
+	}
+	if u.Ingredients != nil {
+		recipe.ID = append([]IngredientIDType(nil), (*u.Ingredients)...)
+	}
+	if u.Servings != nil {
+		recipe.Servings = *u.Servings
+	}
+	if u.Instructions != nil {
+		recipe.Instructions = append([]string(nil), (*u.Instructions)...)
+	}
+	if u.PrepMinutes != nil {
+		recipe.PrepMinutes = *u.PrepMinutes
+	}
+	if u.CookMinutes != nil {
+		recipe.CookMinutes = *u.CookMinutes
+	}
+	if u.Difficulty != nil {
+		recipe.Difficulty = *u.Difficulty
+	}
+	if u.SourceURL != nil {
+		recipe.SourceURL = *u.SourceURL
+	}
+	if u.Notes != nil {
+		recipe.Notes = *u.Notes
+	}
+	if u.CaloriesPerServing != nil {
+		recipe.CaloriesPerServing = *u.CaloriesPerServing
+	}
+}
+
+// RecipeIngredient is a resolved ingredient line of a recipe: the ingredient
+// itself plus the amount the recipe calls for.
+type RecipeIngredient struct {
+	Ingredient
+	Quantity float64
+	Unit     string
+}
+
+// Macros is an amount of energy (kcal) and macronutrients (grams).
+type Macros struct {
+	Calories float64
+	Protein  float64
+	Fat      float64
+	Carbs    float64
+	Fiber    float64
+}
+
+// Nutrition is computed from a recipe's ingredient amounts and the per-gram
+// values stored on each ingredient.
+type Nutrition struct {
+	Total      Macros
+	PerServing Macros
+	// Unmeasured lists the ingredient IDs whose amount could not be converted
+	// to grams (no unit, or no density data) and were left out of the totals.
+	Unmeasured []string `json:",omitempty"`
 }
 
 type Recipe struct {
+	ObjectID    primitive.ObjectID
 	Name        string
-	Ingredients []Ingredient
+	Servings    int
+	Ingredients []RecipeIngredient
+	RecipeDetails
+	Nutrition Nutrition
+	Version   int64
+}
+
+// PantryItem is an amount of an ingredient in a user's pantry. Dates are
+// stored in UTC.
+type PantryItem struct {
+	ObjectID     primitive.ObjectID `bson:"_id,omitempty"`
+	UserID       string             `bson:"user_id"`
+	IngredientID string             `bson:"ingredient_id"`
+	Quantity     float64            `bson:"quantity"`
+	Unit         string             `bson:"unit"`
+	PurchasedAt  time.Time          `bson:"purchased_at"`
+	// ExpiresAt is nil for items that do not go off.
+	ExpiresAt *time.Time `bson:"expires_at,omitempty"`
+}
+
+// PantryItemUpdate holds a partial update of a pantry item; nil fields are left untouched.
+type PantryItemUpdate struct {
+	Quantity    *float64
+	Unit        *string
+	PurchasedAt *time.Time
+	ExpiresAt   *time.Time
+	// ClearExpiresAt marks the item as not going off; ExpiresAt is ignored
+	// then.
+	ClearExpiresAt bool
+}
+
+// Apply copies the non-nil fields of the update onto item.
+func (u PantryItemUpdate) Apply(item *PantryItem) {
+	if u.Quantity != nil {
+		item.Quantity = *u.Quantity
+	}
+	if u.Unit != nil {
+		item.Unit = *u.Unit
+	}
+	if u.PurchasedAt != nil {
+		item.PurchasedAt = *u.PurchasedAt
+	}
+	if u.ClearExpiresAt {
+		item.ExpiresAt = nil
+	} else if u.ExpiresAt != nil {
+		expiresAt := *u.ExpiresAt
+		item.ExpiresAt = &expiresAt
+	}
+}
+
+// ShoppingItem is an amount of an ingredient to buy. Quantity is 0 and Unit
+// empty for ingredients the recipes use without a measured amount.
+type ShoppingItem struct {
+	IngredientID string
+	Name         string
+	Category     string
+	Quantity     float64
+	Unit         string
+}
+
+// ShoppingGroup holds the items of one ingredient category.
+type ShoppingGroup struct {
+	Category string
+	Items    []ShoppingItem
+}
+
+// ShoppingList is a persisted shopping list that its owner shares with other
+// users. Every change increments Version, so clients receiving live updates
+// can drop ones older than what they already have.
+type ShoppingList struct {
+	ObjectID  primitive.ObjectID `bson:"_id,omitempty"`
+	Name      string             `bson:"name"`
+	OwnerID   string             `bson:"owner_id"`
+	Members   []string           `bson:"members"`
+	Items     []ShoppingListItem `bson:"items"`
+	Version   int64              `bson:"version"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+}
+
+// ShoppingListItem is an entry of a shopping list. IngredientID is empty for
+// items that are not ingredients, e.g. "paper towels".
+type ShoppingListItem struct {
+	ItemID       primitive.ObjectID `bson:"item_id"`
+	IngredientID string             `bson:"ingredient_id,omitempty"`
+	Name         string             `bson:"name"`
+	Category     string             `bson:"category"`
+	Quantity     float64            `bson:"quantity"`
+	Unit         string             `bson:"unit"`
+	Checked      bool               `bson:"checked"`
+}
+
+// ShoppingListUpdate holds a partial update of a shopping list; nil fields are left untouched.
+type ShoppingListUpdate struct {
+	Name    *string
+	Members *[]string
+}
+
+// Apply copies the non-nil fields of the update onto list.
+func (u ShoppingListUpdate) Apply(list *ShoppingList) {
+	if u.Name != nil {
+		list.Name = *u.Name
+	}
+	if u.Members != nil {
+		list.Members = append([]string{}, *u.Members...)
+	}
+}
+
+// ShoppingListItemUpdate holds a partial update of a shopping list item; nil fields are left untouched.
+type ShoppingListItemUpdate struct {
+	Name     *string
+	Quantity *float64
+	Unit     *string
+	Checked  *bool
+}
+
+// Apply copies the non-nil fields of the update onto item.
+func (u ShoppingListItemUpdate) Apply(item *ShoppingListItem) {
+	if u.Name != nil {
+		item.Name = *u.Name
+	}
+	if u.Quantity != nil {
+		item.Quantity = *u.Quantity
+	}
+	if u.Unit != nil {
+		item.Unit = *u.Unit
+	}
+	if u.Checked != nil {
+		item.Checked = *u.Checked
+	}
+}
+
+// MealPlanEntry is a recipe a user plans to eat at one meal of a day. Date is
+// midnight UTC of that day.
+type MealPlanEntry struct {
+	ObjectID primitive.ObjectID `bson:"_id,omitempty"`
+	UserID   string             `bson:"user_id"`
+	Date     time.Time          `bson:"date"`
+	Slot     string             `bson:"slot"`
+	RecipeID string             `bson:"recipe_id"`
+	Servings int                `bson:"servings"`
+}
+
+// Meal slots of a day, in the order they are eaten.
+const (
+	SlotBreakfast = "breakfast"
+	SlotLunch     = "lunch"
+	SlotDinner    = "dinner"
+	SlotSnack     = "snack"
+)
+
+// MealSlots lists the valid meal slots in the order they are eaten.
+var MealSlots = []string{SlotBreakfast, SlotLunch, SlotDinner, SlotSnack}
+
+// MealPlanEntryUpdate holds a partial update of a meal plan entry; nil fields are left untouched.
+type MealPlanEntryUpdate struct {
+	Date     *time.Time
+	Slot     *string
+	RecipeID *string
+	Servings *int
+}
+
+// Apply copies the non-nil fields of the update onto entry.
+func (u MealPlanEntryUpdate) Apply(entry *MealPlanEntry) {
+	if u.Date != nil {
+		entry.Date = *u.Date
+	}
+	if u.Slot != nil {
+		entry.Slot = *u.Slot
+	}
+	if u.RecipeID != nil {
+		entry.RecipeID = *u.RecipeID
+	}
+	if u.Servings != nil {
+		entry.Servings = *u.Servings
+	}
+}
+
+// PlannedMeal is a meal plan entry with the recipe it refers to and the
+// nutrition of the planned servings. EntryID is zero for meals of a generated
+// plan that has not been saved.
+type PlannedMeal struct {
+	EntryID    primitive.ObjectID
+	Slot       string
+	RecipeID   string
+	RecipeName string
+	Servings   int
+	Nutrition  Macros
+	// RecipeMissing is set when the recipe has been deleted since it was
+	// planned; the meal then counts for nothing in the totals.
+	RecipeMissing bool `json:",omitempty"`
+}
+
+// MealPlanDay holds the meals planned for one day and their totals.
+type MealPlanDay struct {
+	Date  string
+	Meals []PlannedMeal
+	Total Macros
+	// Incomplete is set when an ingredient amount of a planned recipe could
+	// not be converted to grams, so Total is lower than it should be.
+	Incomplete bool `json:",omitempty"`
+}
+
+// MealPlan is a user's meal plan for a range of days, From and To included.
+type MealPlan struct {
+	From  string
+	To    string
+	Days  []MealPlanDay
+	Total Macros
+}
+
+// GeneratedMealPlan is a meal plan proposed for a daily calorie target. It is
+// not saved; generating again with the same Seed, recipes and constraints
+// gives the same plan.
+type GeneratedMealPlan struct {
+	MealPlan
+	Seed   int64
+	Target float64
+	// Candidates counts the recipes that met the constraints.
+	Candidates   int
+	Explanations []DayExplanation
+}
+
+// DayExplanation accounts for how close a generated day comes to the target.
+type DayExplanation struct {
+	Date     string
+	Calories float64
+	Target   float64
+	// Difference is Calories minus Target.
+	Difference float64
+	Text       string
 }
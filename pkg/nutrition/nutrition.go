@@ -0,0 +1,79 @@
+package nutrition
+
+import (
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/units"
+	"math"
+)
+
+// Compute totals the calories and macronutrients of a recipe's ingredient lines
+// and divides them over the given number of servings. A recipe without a
+// serving count is treated as a single serving.
+func Compute(lines []model.RecipeIngredient, servings int) model.Nutrition {
+	var result model.Nutrition
+	for _, line := range lines {
+		if line.Unit == "" {
+			result.Unmeasured = append(result.Unmeasured, line.ObjectID.Hex())
+			continue
+		}
+		grams, err := units.ToGrams(line.Quantity, line.Unit, line.Conversion())
+		if err != nil {
+			result.Unmeasured = append(result.Unmeasured, line.ObjectID.Hex())
+			continue
+		}
+
+		result.Total.Calories += grams * float64(line.Calories)
+		result.Total.Protein += grams * line.Protein
+		result.Total.Fat += grams * line.Fat
+		result.Total.Carbs += grams * line.Carbs
+		result.Total.Fiber += grams * line.Fiber
+	}
+
+	if servings <= 0 {
+		servings = 1
+	}
+	result.PerServing = round(scale(result.Total, 1/float64(servings)))
+	result.Total = round(result.Total)
+	return result
+}
+
+func scale(m model.Macros, factor float64) model.Macros {
+	return model.Macros{
+		Calories: m.Calories * factor,
+		Protein:  m.Protein * factor,
+		Fat:      m.Fat * factor,
+		Carbs:    m.Carbs * factor,
+		Fiber:    m.Fiber * factor,
+	}
+}
+
+// round keeps one decimal, which is more precision than the inputs warrant.
+func round(m model.Macros) model.Macros {
+	r := func(v float64) float64 { return math.Round(v*10) / 10 }
+	return model.Macros{
+		Calories: r(m.Calories),
+		Protein:  r(m.Protein),
+		Fat:      r(m.Fat),
+		Carbs:    r(m.Carbs),
+		Fiber:    r(m.Fiber),
+	}
+}
+
+// ForServings returns the nutrition of the given number of servings of a
+// recipe with nutrition n.
+func ForServings(n model.Nutrition, servings int) model.Macros {
+	return round(scale(n.PerServing, float64(servings)))
+}
+
+// Sum adds up the given amounts.
+func Sum(amounts ...model.Macros) model.Macros {
+	var total model.Macros
+	for _, m := range amounts {
+		total.Calories += m.Calories
+		total.Protein += m.Protein
+		total.Fat += m.Fat
+		total.Carbs += m.Carbs
+		total.Fiber += m.Fiber
+	}
+	return round(total)
+}
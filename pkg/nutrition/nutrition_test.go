@@ -0,0 +1,86 @@
+package nutrition
+
+import (
+	"dynamicrecipes/pkg/model"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCompute(t *testing.T) {
+	flour := model.Ingredient{ObjectID: primitive.NewObjectID(), Name: "flour", Calories: 4, Protein: 0.1, Carbs: 0.75, Fiber: 0.03}
+	milk := model.Ingredient{ObjectID: primitive.NewObjectID(), Name: "milk", Calories: 1, Density: 1.03, Protein: 0.03, Fat: 0.04}
+	egg := model.Ingredient{ObjectID: primitive.NewObjectID(), Name: "egg", Calories: 1, GramsPerPiece: 50, Protein: 0.13, Fat: 0.1}
+	salt := model.Ingredient{ObjectID: primitive.NewObjectID(), Name: "salt"}
+	line := func(i model.Ingredient, q float64, unit string) model.RecipeIngredient {
+		return model.RecipeIngredient{Ingredient: i, Quantity: q, Unit: unit}
+	}
+
+	tests := []struct {
+		name     string
+		lines    []model.RecipeIngredient
+		servings int
+		want     model.Nutrition
+	}{
+		{
+			name:     "no lines",
+			servings: 2,
+		},
+		{
+			name:     "grams",
+			lines:    []model.RecipeIngredient{line(flour, 200, "g")},
+			servings: 2,
+			want: model.Nutrition{
+				Total:      model.Macros{Calories: 800, Protein: 20, Carbs: 150, Fiber: 6},
+				PerServing: model.Macros{Calories: 400, Protein: 10, Carbs: 75, Fiber: 3},
+			},
+		},
+		{
+			name:     "volume and count through density",
+			lines:    []model.RecipeIngredient{line(flour, 0.1, "kg"), line(milk, 100, "ml"), line(egg, 2, "pc")},
+			servings: 4,
+			want: model.Nutrition{
+				// 100 g flour, 103 g milk and 100 g egg.
+				Total:      model.Macros{Calories: 603, Protein: 26.1, Fat: 14.1, Carbs: 75, Fiber: 3},
+				PerServing: model.Macros{Calories: 150.8, Protein: 6.5, Fat: 3.5, Carbs: 18.8, Fiber: 0.8},
+			},
+		},
+		{
+			name:     "per serving is rounded to one decimal",
+			lines:    []model.RecipeIngredient{line(egg, 100, "g")},
+			servings: 3,
+			want: model.Nutrition{
+				Total:      model.Macros{Calories: 100, Protein: 13, Fat: 10},
+				PerServing: model.Macros{Calories: 33.3, Protein: 4.3, Fat: 3.3},
+			},
+		},
+		{
+			name:     "no servings counts as one",
+			lines:    []model.RecipeIngredient{line(flour, 50, "g")},
+			servings: 0,
+			want: model.Nutrition{
+				Total:      model.Macros{Calories: 200, Protein: 5, Carbs: 37.5, Fiber: 1.5},
+				PerServing: model.Macros{Calories: 200, Protein: 5, Carbs: 37.5, Fiber: 1.5},
+			},
+		},
+		{
+			name:     "unconvertible lines are left out",
+			lines:    []model.RecipeIngredient{line(flour, 1, "cup"), line(salt, 1, ""), line(egg, 1, "pinch"), line(milk, 200, "g")},
+			servings: 2,
+			want: model.Nutrition{
+				Total:      model.Macros{Calories: 200, Protein: 6, Fat: 8},
+				PerServing: model.Macros{Calories: 100, Protein: 3, Fat: 4},
+				Unmeasured: []string{flour.ObjectID.Hex(), salt.ObjectID.Hex(), egg.ObjectID.Hex()},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Compute(tt.lines, tt.servings); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Compute() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,70 +3,189 @@ package repository
 import (
 	"context"
 	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/store"
+	"errors"
 	"fmt"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// DeleteMode decides what happens to recipes that still reference an
+// ingredient being deleted.
+type DeleteMode string
+
+const (
+	// DeleteRestrict refuses to delete a referenced ingredient.
+	DeleteRestrict DeleteMode = ""
+	// DeleteCascade deletes the referencing recipes as well.
+	DeleteCascade DeleteMode = "cascade"
+	// DeleteDetach removes the ingredient from the referencing recipes.
+	DeleteDetach DeleteMode = "detach"
+)
+
+// IngredientInUseError is returned by DeleteByName in DeleteRestrict mode when
+// recipes still reference the ingredient.
+type IngredientInUseError struct {
+	Ingredient model.Ingredient
+	Recipes    []model.RecipeReturnType
+}
+
+func (e *IngredientInUseError) Error() string {
+	return fmt.Sprintf("ingredient %q is used by %d recipe(s)", e.Ingredient.Name, len(e.Recipes))
+}
+
 // IngredientRepository handles database operations related to ingredients.
 type IngredientRepository struct {
-	client *mongo.Client
+	store   store.IngredientStore
+	recipes store.RecipeStore
 }
 
 // NewIngredientRepository creates a new IngredientRepository.
-func NewIngredientRepository(client *mongo.Client) *IngredientRepository {
-	return &IngredientRepository{client: client}
+func NewIngredientRepository(s store.Store) *IngredientRepository {
+	return &IngredientRepository{store: s.Ingredients(), recipes: s.Recipes()}
 }
 
 // FindByID finds an ingredient by its ID.
 func (r *IngredientRepository) FindByID(ctx context.Context, ingredientID string) (*model.Ingredient, error) {
-	collection := r.client.Database("Recipe_Service").Collection("Ingredients")
 	objID, err := primitive.ObjectIDFromHex(ingredientID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid ingredient ID: %w", err)
 	}
 
-	filter := bson.D{{Key: "_id", Value: objID}}
-	var ingredient model.Ingredient
-	if err := collection.FindOne(ctx, filter).Decode(&ingredient); err != nil {
+	ingredient, err := r.store.FindByID(ctx, objID)
+	if err != nil {
 		return nil, fmt.Errorf("failed to find ingredient: %w", err)
 	}
-	return &ingredient, nil
+	return ingredient, nil
 }
 
-func (r *IngredientRepository) DeleteByName(ctx context.Context, ingredientName string) (*mongo.DeleteResult, error) {
-	collection := r.client.Database("Recipe_Service").Collection("Ingredients")
+// FindByIDs fetches the ingredients with the given IDs in one query and returns
+// them keyed by their hex ID. Unknown IDs are absent from the map.
+func (r *IngredientRepository) FindByIDs(ctx context.Context, ingredientIDs []string) (map[string]model.Ingredient, error) {
+	objIDs := make([]primitive.ObjectID, 0, len(ingredientIDs))
+	for _, ingredientID := range ingredientIDs {
+		objID, err := primitive.ObjectIDFromHex(ingredientID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ingredient ID: %w", err)
+		}
+		objIDs = append(objIDs, objID)
+	}
 
-	filter := bson.M{"name": ingredientName}
+	ingredients, err := r.store.FindByIDs(ctx, objIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find ingredients: %w", err)
+	}
 
-	result, err := collection.DeleteOne(ctx, filter)
+	byID := make(map[string]model.Ingredient, len(ingredients))
+	for _, ingredient := range ingredients {
+		byID[ingredient.ObjectID.Hex()] = ingredient
+	}
+	return byID, nil
+}
 
-	return result, err
+// Page returns one page of ingredients selected by params, along with the
+// cursor of the next page or "" on the last one. Errors caused by params wrap
+// ErrInvalidListParams.
+func (r *IngredientRepository) Page(ctx context.Context, params ListParams) ([]model.Ingredient, string, error) {
+	q, err := params.query(store.SortCreated, store.SortName, store.SortCalories)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ingredients, err := r.store.Query(ctx, q)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list ingredients: %w", err)
+	}
+	ingredients, next := nextPage(ingredients, q, store.IngredientPosition)
+	return ingredients, next, nil
 }
 
-// UpdateByID updates an ingredient identified by its ID with the given update data.
-func (r *IngredientRepository) UpdateByID(ctx context.Context, ingredientID string, updateData bson.M) (*model.Ingredient, error) {
-	collection := r.client.Database("Recipe_Service").Collection("Ingredients")
+// DeleteByName deletes the ingredient with the given name and returns it, or
+// nil if there was none, along with the recipes that referenced it. The
+// ingredient must still be at version; otherwise the returned error wraps
+// store.ErrVersionConflict. What happens to the recipes depends on mode; in
+// DeleteRestrict mode an *IngredientInUseError is returned and nothing is
+// deleted. Pantry items holding the ingredient are left alone; the pantry
+// hides them from then on.
+//
+// The ingredient is deleted before its recipes are changed, so that a
+// concurrent delete of the same ingredient leaves them alone. The steps are
+// not transactional though: a recipe created between the reference check and
+// the delete can still end up dangling.
+func (r *IngredientRepository) DeleteByName(ctx context.Context, ingredientName string, version int64, mode DeleteMode) (*model.Ingredient, []model.RecipeReturnType, error) {
+	ingredient, err := r.store.FindByName(ctx, ingredientName)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil, nil // No document was found with the provided name
+		}
+		return nil, nil, fmt.Errorf("failed to find ingredient: %w", err)
+	}
+	if ingredient.Version != version {
+		return nil, nil, fmt.Errorf("failed to delete ingredient: %w", store.ErrVersionConflict)
+	}
+
+	referencing, err := r.recipes.FindByIngredient(ctx, ingredient.ObjectID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find referencing recipes: %w", err)
+	}
+	if len(referencing) > 0 && mode != DeleteCascade && mode != DeleteDetach {
+		return nil, nil, &IngredientInUseError{Ingredient: *ingredient, Recipes: referencing}
+	}
 
+	if err := r.store.DeleteByID(ctx, ingredient.ObjectID, version); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil, nil // Deleted concurrently; that delete handles the recipes
+		}
+		return nil, nil, fmt.Errorf("failed to delete ingredient: %w", err)
+	}
+
+	if len(referencing) > 0 {
+		switch mode {
+		case DeleteCascade:
+			if _, err := r.recipes.DeleteByIngredient(ctx, ingredient.ObjectID); err != nil {
+				return nil, nil, fmt.Errorf("failed to delete referencing recipes: %w", err)
+			}
+		case DeleteDetach:
+			if _, err := r.recipes.RemoveIngredient(ctx, ingredient.ObjectID); err != nil {
+				return nil, nil, fmt.Errorf("failed to detach ingredient from recipes: %w", err)
+			}
+			// The ingredient is gone, so it no longer counts towards
+			// the calories of the recipes it was detached from.
+			if err := refreshCalories(ctx, r.recipes, r.store, referencing); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return ingredient, referencing, nil
+}
+
+// UpdateByID updates an ingredient identified by its ID with the given update
+// data and returns it as updated. Changes to its calories or conversions are
+// carried over to the calories per serving of the recipes using it. The
+// ingredient must still be at version; otherwise the returned error wraps
+// store.ErrVersionConflict.
+func (r *IngredientRepository) UpdateByID(ctx context.Context, ingredientID string, version int64, updateData model.IngredientUpdate) (*model.Ingredient, error) {
 	objID, err := primitive.ObjectIDFromHex(ingredientID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid ingredient ID: %w", err)
 	}
 
-	// Create an update document
-	update := bson.M{"$set": updateData}
-
-	// Find the document and update it
-	var updatedIngredient model.Ingredient
-	err = collection.FindOneAndUpdate(ctx, bson.M{"_id": objID}, update).Decode(&updatedIngredient)
+	updatedIngredient, err := r.store.UpdateByID(ctx, objID, version, updateData)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if errors.Is(err, store.ErrNotFound) {
 			return nil, nil // No document was found with the provided ID
 		}
 		return nil, fmt.Errorf("failed to update ingredient: %w", err)
 	}
 
-	return &updatedIngredient, nil
+	if updateData.Calories != nil || updateData.Density != nil || updateData.GramsPerPiece != nil {
+		referencing, err := r.recipes.FindByIngredient(ctx, objID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find referencing recipes: %w", err)
+		}
+		if err := refreshCalories(ctx, r.recipes, r.store, referencing); err != nil {
+			return nil, err
+		}
+	}
+	return updatedIngredient, nil
 }
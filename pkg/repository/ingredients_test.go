@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/store"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// racingStore is a Store whose ingredients are deleted by someone else
+// between being looked up and being deleted.
+type racingStore struct {
+	store.Store
+}
+
+func (s racingStore) Ingredients() store.IngredientStore {
+	return racingIngredientStore{s.Store.Ingredients()}
+}
+
+type racingIngredientStore struct {
+	store.IngredientStore
+}
+
+func (s racingIngredientStore) DeleteByID(ctx context.Context, id primitive.ObjectID, version int64) error {
+	if err := s.IngredientStore.DeleteByID(ctx, id, version); err != nil {
+		return err
+	}
+	return store.ErrNotFound
+}
+
+func TestDeleteByNameLeavesRecipesWhenDeletedConcurrently(t *testing.T) {
+	for _, mode := range []DeleteMode{DeleteCascade, DeleteDetach} {
+		t.Run(string(mode), func(t *testing.T) {
+			ctx := context.Background()
+			s := store.NewMemoryStore()
+			ids, err := s.Ingredients().InsertMany(ctx, []model.Ingredient{{Name: "flour"}, {Name: "egg"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := []model.IngredientIDType{{ObjectID: ids[0].Hex()}, {ObjectID: ids[1].Hex()}}
+			recipeIDs, err := s.Recipes().InsertMany(ctx, []model.RecipePostType{{Name: "pancakes", Ingredients: lines}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			deleted, affected, err := NewIngredientRepository(racingStore{s}).DeleteByName(ctx, "egg", 1, mode)
+			if err != nil {
+				t.Fatalf("DeleteByName: %v", err)
+			}
+			if deleted != nil || affected != nil {
+				t.Errorf("DeleteByName = %v, %v; want nothing deleted or affected", deleted, affected)
+			}
+			recipe, err := s.Recipes().FindByID(ctx, recipeIDs[0])
+			if err != nil {
+				t.Fatalf("the recipe is gone: %v", err)
+			}
+			if len(recipe.ID) != 2 || recipe.Version != 1 {
+				t.Errorf("the recipe was changed to %+v", recipe)
+			}
+		})
+	}
+}
+
+func TestDeleteByNameStaleVersion(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	ids, err := s.Ingredients().InsertMany(ctx, []model.Ingredient{{Name: "egg"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := "egg"
+	if _, err := s.Ingredients().UpdateByID(ctx, ids[0], 1, model.IngredientUpdate{Name: &name}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := NewIngredientRepository(s).DeleteByName(ctx, "egg", 1, DeleteCascade); !errors.Is(err, store.ErrVersionConflict) {
+		t.Fatalf("DeleteByName at a stale version: got %v, want ErrVersionConflict", err)
+	}
+	if _, err := s.Ingredients().FindByID(ctx, ids[0]); err != nil {
+		t.Errorf("the ingredient was deleted: %v", err)
+	}
+}
+
+func TestDeleteByNameRestrict(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	ids, err := s.Ingredients().InsertMany(ctx, []model.Ingredient{{Name: "egg"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Recipes().InsertMany(ctx, []model.RecipePostType{{Name: "omelette", Ingredients: []model.IngredientIDType{{ObjectID: ids[0].Hex()}}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = NewIngredientRepository(s).DeleteByName(ctx, "egg", 1, DeleteRestrict)
+	var inUse *IngredientInUseError
+	if !errors.As(err, &inUse) || len(inUse.Recipes) != 1 {
+		t.Fatalf("DeleteByName in restrict mode: got %v, want an IngredientInUseError naming the omelette", err)
+	}
+	if _, err := s.Ingredients().FindByID(ctx, ids[0]); err != nil {
+		t.Errorf("the ingredient was deleted: %v", err)
+	}
+}
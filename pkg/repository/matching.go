@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MissingIngredient is an ingredient line of a recipe that is not at hand.
+type MissingIngredient struct {
+	ObjectID string
+	Name     string
+	Quantity float64
+	Unit     string
+}
+
+// RecipeMatch rates how completely a recipe can be made from the ingredients
+// at hand.
+type RecipeMatch struct {
+	ObjectID primitive.ObjectID
+	Name     string
+	Servings int
+	// Have and Total count the distinct ingredients of the recipe that are
+	// at hand and that it needs; Coverage is their ratio.
+	Have     int
+	Total    int
+	Coverage float64
+	Missing  []MissingIngredient
+}
+
+// MatchOptions narrows the result of Match.
+type MatchOptions struct {
+	// Limit caps the number of matches; 0 means no limit.
+	Limit int
+	// MaxMissing, when set, drops recipes missing more ingredients.
+	MaxMissing *int
+}
+
+// Match ranks the recipes that use at least one of the given ingredients by
+// the share of their ingredients at hand, best first, and lists what is
+// missing from each. It reads every recipe with a single List and the missing
+// ingredients with a single FindByIDs, so the cost does not grow with lookups
+// per recipe.
+func (r *RecipeRepository) Match(ctx context.Context, ingredientIDs []string, opts MatchOptions) ([]RecipeMatch, error) {
+	have := make(map[string]bool, len(ingredientIDs))
+	for _, ingredientID := range ingredientIDs {
+		objID, err := primitive.ObjectIDFromHex(ingredientID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ingredient ID: %w", err)
+		}
+		have[objID.Hex()] = true
+	}
+
+	recipes, err := r.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recipes: %w", err)
+	}
+
+	matches := make([]RecipeMatch, 0)
+	for _, recipe := range recipes {
+		match := RecipeMatch{ObjectID: recipe.ObjectID, Name: recipe.Name, Servings: recipe.Servings, Missing: []MissingIngredient{}}
+		seen := make(map[string]bool, len(recipe.ID))
+		for _, line := range recipe.ID {
+			if seen[line.ObjectID] {
+				continue
+			}
+			seen[line.ObjectID] = true
+			match.Total++
+			if have[line.ObjectID] {
+				match.Have++
+			} else {
+				match.Missing = append(match.Missing, MissingIngredient{ObjectID: line.ObjectID, Quantity: line.Quantity, Unit: line.Unit})
+			}
+		}
+		if match.Have == 0 {
+			continue
+		}
+		if opts.MaxMissing != nil && len(match.Missing) > *opts.MaxMissing {
+			continue
+		}
+		match.Coverage = float64(match.Have) / float64(match.Total)
+		matches = append(matches, match)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Coverage != b.Coverage {
+			return a.Coverage > b.Coverage
+		}
+		if len(a.Missing) != len(b.Missing) {
+			return len(a.Missing) < len(b.Missing)
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ObjectID.Hex() < b.ObjectID.Hex()
+	})
+	if opts.Limit > 0 && len(matches) > opts.Limit {
+		matches = matches[:opts.Limit]
+	}
+
+	if err := r.nameMissing(ctx, matches); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// nameMissing fills in the names of the missing ingredients of matches.
+func (r *RecipeRepository) nameMissing(ctx context.Context, matches []RecipeMatch) error {
+	var objIDs []primitive.ObjectID
+	seen := make(map[string]bool)
+	for _, match := range matches {
+		for _, missing := range match.Missing {
+			if seen[missing.ObjectID] {
+				continue
+			}
+			seen[missing.ObjectID] = true
+			objID, err := primitive.ObjectIDFromHex(missing.ObjectID)
+			if err != nil {
+				return fmt.Errorf("invalid ingredient ID: %w", err)
+			}
+			objIDs = append(objIDs, objID)
+		}
+	}
+	if len(objIDs) == 0 {
+		return nil
+	}
+
+	ingredients, err := r.ingredients.FindByIDs(ctx, objIDs)
+	if err != nil {
+		return fmt.Errorf("failed to look up ingredients: %w", err)
+	}
+	names := make(map[string]string, len(ingredients))
+	for _, ingredient := range ingredients {
+		names[ingredient.ObjectID.Hex()] = ingredient.Name
+	}
+	for _, match := range matches {
+		for i := range match.Missing {
+			match.Missing[i].Name = names[match.Missing[i].ObjectID]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/store"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	names := []string{"flour", "egg", "milk", "butter", "salt", "sugar", "bread"}
+	var ingredients []model.Ingredient
+	for _, name := range names {
+		ingredients = append(ingredients, model.Ingredient{Name: name})
+	}
+	ids, err := s.Ingredients().InsertMany(ctx, ingredients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make(map[string]string, len(names))
+	for i, name := range names {
+		id[name] = ids[i].Hex()
+	}
+	recipe := func(name string, lines ...string) model.RecipePostType {
+		recipe := model.RecipePostType{Name: name, Servings: 2}
+		for _, line := range lines {
+			recipe.Ingredients = append(recipe.Ingredients, model.IngredientIDType{ObjectID: id[line], Quantity: 50, Unit: "g"})
+		}
+		return recipe
+	}
+	_, err = s.Recipes().InsertMany(ctx, []model.RecipePostType{
+		recipe("shortbread", "flour", "sugar", "butter"),
+		recipe("scrambled eggs", "egg", "butter", "egg"),
+		recipe("crepes", "flour", "egg", "milk", "butter"),
+		recipe("toast", "bread", "butter"),
+		recipe("omelette", "egg", "salt"),
+		recipe("pancakes", "flour", "egg", "milk"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	intp := func(n int) *int { return &n }
+	tests := []struct {
+		name string
+		have []string
+		opts MatchOptions
+		want []string
+	}{
+		{
+			name: "ranked by coverage, then fewest missing, then name",
+			have: []string{"flour", "egg", "milk"},
+			want: []string{
+				"pancakes 3/3 1.00 []",
+				"crepes 3/4 0.75 [butter 50 g]",
+				"omelette 1/2 0.50 [salt 50 g]",
+				// egg is counted once, though it has two lines.
+				"scrambled eggs 1/2 0.50 [butter 50 g]",
+				"shortbread 1/3 0.33 [sugar 50 g, butter 50 g]",
+			},
+		},
+		{
+			name: "nothing missing",
+			have: []string{"flour", "egg", "milk"},
+			opts: MatchOptions{MaxMissing: intp(0)},
+			want: []string{"pancakes 3/3 1.00 []"},
+		},
+		{
+			name: "at most one missing",
+			have: []string{"flour", "egg", "milk"},
+			opts: MatchOptions{MaxMissing: intp(1)},
+			want: []string{
+				"pancakes 3/3 1.00 []",
+				"crepes 3/4 0.75 [butter 50 g]",
+				"omelette 1/2 0.50 [salt 50 g]",
+				"scrambled eggs 1/2 0.50 [butter 50 g]",
+			},
+		},
+		{
+			name: "limit",
+			have: []string{"flour", "egg", "milk"},
+			opts: MatchOptions{Limit: 2},
+			want: []string{"pancakes 3/3 1.00 []", "crepes 3/4 0.75 [butter 50 g]"},
+		},
+		{
+			name: "limit after the threshold",
+			have: []string{"butter"},
+			opts: MatchOptions{MaxMissing: intp(1), Limit: 1},
+			want: []string{"scrambled eggs 1/2 0.50 [egg 50 g]"},
+		},
+		{
+			name: "recipes using none of the ingredients are left out",
+			have: []string{"bread"},
+			want: []string{"toast 1/2 0.50 [butter 50 g]"},
+		},
+		{
+			name: "no ingredients",
+		},
+	}
+	repo := NewRecipeRepository(s)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var have []string
+			for _, name := range tt.have {
+				have = append(have, id[name])
+			}
+			matches, err := repo.Match(ctx, have, tt.opts)
+			if err != nil {
+				t.Fatalf("Match: %v", err)
+			}
+			var got []string
+			for _, match := range matches {
+				var missing []string
+				for _, m := range match.Missing {
+					if m.ObjectID != id[m.Name] {
+						t.Errorf("%s: missing %s has ID %s", match.Name, m.Name, m.ObjectID)
+					}
+					missing = append(missing, fmt.Sprintf("%s %g %s", m.Name, m.Quantity, m.Unit))
+				}
+				got = append(got, fmt.Sprintf("%s %d/%d %.2f [%s]", match.Name, match.Have, match.Total, match.Coverage, strings.Join(missing, ", ")))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Match() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := repo.Match(ctx, []string{"not an ID"}, MatchOptions{}); err == nil {
+		t.Error("Match accepted an invalid ingredient ID")
+	}
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/store"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrUnknownRecipe is wrapped by the error Insert and UpdateByID return when
+// meal plan entries reference recipes that do not exist.
+var ErrUnknownRecipe = errors.New("unknown recipe")
+
+// MealPlanRepository handles database operations related to users' meal
+// plans. Every method is scoped to one user; entries of other users are
+// reported as not found.
+type MealPlanRepository struct {
+	store   store.MealPlanStore
+	recipes store.RecipeStore
+}
+
+// NewMealPlanRepository creates a new MealPlanRepository.
+func NewMealPlanRepository(s store.Store) *MealPlanRepository {
+	return &MealPlanRepository{store: s.MealPlans(), recipes: s.Recipes()}
+}
+
+// List returns the entries of the user's meal plan dated from from up to but
+// excluding to.
+func (r *MealPlanRepository) List(ctx context.Context, userID string, from, to time.Time) ([]model.MealPlanEntry, error) {
+	entries, err := r.store.ListByUser(ctx, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list meal plan: %w", err)
+	}
+	return entries, nil
+}
+
+// FindByID finds an entry of the user's meal plan by its ID. The returned
+// error wraps store.ErrNotFound if the entry does not exist or belongs to
+// another user.
+func (r *MealPlanRepository) FindByID(ctx context.Context, userID, entryID string) (*model.MealPlanEntry, error) {
+	objID, err := primitive.ObjectIDFromHex(entryID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid meal plan entry ID: %w", err)
+	}
+
+	entry, err := r.store.FindByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find meal plan entry: %w", err)
+	}
+	if entry.UserID != userID {
+		return nil, fmt.Errorf("failed to find meal plan entry: %w", store.ErrNotFound)
+	}
+	return entry, nil
+}
+
+// checkRecipes verifies with a single lookup that every given recipe ID
+// refers to an existing recipe, and returns the IDs in canonical form.
+func (r *MealPlanRepository) checkRecipes(ctx context.Context, recipeIDs []string) ([]string, error) {
+	objIDs := make([]primitive.ObjectID, 0, len(recipeIDs))
+	canonical := make([]string, 0, len(recipeIDs))
+	for _, recipeID := range recipeIDs {
+		objID, err := primitive.ObjectIDFromHex(recipeID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipe ID: %w", err)
+		}
+		objIDs = append(objIDs, objID)
+		canonical = append(canonical, objID.Hex())
+	}
+
+	found, err := r.recipes.FindByIDs(ctx, objIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up recipes: %w", err)
+	}
+	exists := make(map[string]bool, len(found))
+	for _, recipe := range found {
+		exists[recipe.ObjectID.Hex()] = true
+	}
+	var unknown []string
+	for _, recipeID := range canonical {
+		if !exists[recipeID] {
+			unknown = append(unknown, recipeID)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRecipe, strings.Join(unknown, ", "))
+	}
+	return canonical, nil
+}
+
+// Insert adds the given entries to the user's meal plan and returns their
+// generated IDs. Every entry must reference an existing recipe; otherwise the
+// returned error wraps ErrUnknownRecipe and nothing is written.
+func (r *MealPlanRepository) Insert(ctx context.Context, userID string, entries []model.MealPlanEntry) ([]primitive.ObjectID, error) {
+	recipeIDs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		recipeIDs = append(recipeIDs, entry.RecipeID)
+	}
+	canonical, err := r.checkRecipes(ctx, recipeIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].UserID = userID
+		entries[i].RecipeID = canonical[i]
+	}
+
+	ids, err := r.store.InsertMany(ctx, entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert meal plan entries: %w", err)
+	}
+	return ids, nil
+}
+
+// UpdateByID applies a partial update to an entry of the user's meal plan and
+// returns it as updated, or nil if there is no such entry.
+func (r *MealPlanRepository) UpdateByID(ctx context.Context, userID, entryID string, updateData model.MealPlanEntryUpdate) (*model.MealPlanEntry, error) {
+	entry, err := r.FindByID(ctx, userID, entryID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil // No entry was found with the provided ID
+		}
+		return nil, err
+	}
+	if updateData.RecipeID != nil {
+		canonical, err := r.checkRecipes(ctx, []string{*updateData.RecipeID})
+		if err != nil {
+			return nil, err
+		}
+		updateData.RecipeID = &canonical[0]
+	}
+
+	updatedEntry, err := r.store.UpdateByID(ctx, entry.ObjectID, updateData)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil // Deleted concurrently
+		}
+		return nil, fmt.Errorf("failed to update meal plan entry: %w", err)
+	}
+	return updatedEntry, nil
+}
+
+// DeleteByID deletes an entry of the user's meal plan and reports how many
+// were removed.
+func (r *MealPlanRepository) DeleteByID(ctx context.Context, userID, entryID string) (int64, error) {
+	entry, err := r.FindByID(ctx, userID, entryID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return r.store.DeleteByID(ctx, entry.ObjectID)
+}
+
+// DeleteRange clears the user's meal plan from from up to but excluding to
+// and reports how many entries were removed.
+func (r *MealPlanRepository) DeleteRange(ctx context.Context, userID string, from, to time.Time) (int64, error) {
+	deleted, err := r.store.DeleteByUser(ctx, userID, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear meal plan: %w", err)
+	}
+	return deleted, nil
+}
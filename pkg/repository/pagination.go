@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"dynamicrecipes/pkg/store"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidListParams is wrapped by every error caused by bad ListParams.
+var ErrInvalidListParams = errors.New("invalid list parameters")
+
+const (
+	// DefaultPageSize is used when ListParams.Limit is 0.
+	DefaultPageSize = 50
+	// MaxPageSize caps ListParams.Limit.
+	MaxPageSize = 500
+)
+
+// ListParams are the client-facing options of a paged listing.
+type ListParams struct {
+	Limit int
+	// Sort is "created", "name" or "calories", prefixed with "-" for
+	// descending order. It defaults to "created".
+	Sort string
+	// Cursor is the opaque value returned with the previous page. The other
+	// parameters must be repeated unchanged when it is used.
+	Cursor      string
+	NamePrefix  string
+	MinCalories *int
+	MaxCalories *int
+}
+
+// cursor is the decoded form of ListParams.Cursor. It records the sort it was
+// issued for, so that it cannot be replayed against a different order.
+type cursor struct {
+	Sort     store.SortField `json:"s"`
+	Desc     bool            `json:"d,omitempty"`
+	Name     string          `json:"n,omitempty"`
+	Calories int             `json:"c,omitempty"`
+	ID       string          `json:"i"`
+}
+
+// query validates p and turns it into a store query that fetches one item
+// more than the page size, to tell whether another page follows.
+func (p ListParams) query(sorts ...store.SortField) (store.ListQuery, error) {
+	var q store.ListQuery
+
+	q.Limit = p.Limit
+	if q.Limit == 0 {
+		q.Limit = DefaultPageSize
+	}
+	if q.Limit < 0 || q.Limit > MaxPageSize {
+		return q, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidListParams, MaxPageSize)
+	}
+	q.Limit++
+
+	q.Sort = store.SortCreated
+	if p.Sort != "" {
+		q.Desc = strings.HasPrefix(p.Sort, "-")
+		q.Sort = store.SortField(strings.TrimPrefix(p.Sort, "-"))
+	}
+	supported := false
+	for _, sort := range sorts {
+		supported = supported || sort == q.Sort
+	}
+	if !supported {
+		return q, fmt.Errorf("%w: cannot sort by %q", ErrInvalidListParams, q.Sort)
+	}
+
+	if p.MinCalories != nil && p.MaxCalories != nil && *p.MinCalories > *p.MaxCalories {
+		return q, fmt.Errorf("%w: minCalories is greater than maxCalories", ErrInvalidListParams)
+	}
+	q.NamePrefix = p.NamePrefix
+	q.MinCalories = p.MinCalories
+	q.MaxCalories = p.MaxCalories
+
+	if p.Cursor != "" {
+		after, err := decodeCursor(p.Cursor, q)
+		if err != nil {
+			return q, err
+		}
+		q.After = &after
+	}
+	return q, nil
+}
+
+func encodeCursor(q store.ListQuery, after store.Position) string {
+	c := cursor{Sort: q.Sort, Desc: q.Desc, ID: after.ID.Hex()}
+	switch q.Sort {
+	case store.SortName:
+		c.Name = after.Name
+	case store.SortCalories:
+		c.Calories = after.Calories
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(value string, q store.ListQuery) (store.Position, error) {
+	invalid := fmt.Errorf("%w: malformed cursor", ErrInvalidListParams)
+
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return store.Position{}, invalid
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return store.Position{}, invalid
+	}
+	id, err := primitive.ObjectIDFromHex(c.ID)
+	if err != nil {
+		return store.Position{}, invalid
+	}
+	if c.Sort != q.Sort || c.Desc != q.Desc {
+		return store.Position{}, fmt.Errorf("%w: cursor was issued for a different sort", ErrInvalidListParams)
+	}
+	return store.Position{Name: c.Name, Calories: c.Calories, ID: id}, nil
+}
+
+// nextPage trims the extra item fetched by query and returns the cursor of the
+// page after items, or "" if this is the last one.
+func nextPage[T any](items []T, q store.ListQuery, position func(T) store.Position) ([]T, string) {
+	pageSize := q.Limit - 1
+	if items == nil {
+		items = []T{} // an empty page is still a JSON array
+	}
+	if len(items) <= pageSize {
+		return items, ""
+	}
+	items = items[:pageSize]
+	return items, encodeCursor(q, position(items[pageSize-1]))
+}
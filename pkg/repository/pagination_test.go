@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/store"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// seedMenu stores recipes whose calories per serving, noted beside them, tie
+// in pairs, so that paging by calories has to break ties by ID.
+func seedMenu(t *testing.T) store.Store {
+	t.Helper()
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	ids, err := s.Ingredients().InsertMany(ctx, []model.Ingredient{{Name: "light", Calories: 1}, {Name: "rich", Calories: 5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := func(i int, grams float64) []model.IngredientIDType {
+		return []model.IngredientIDType{{ObjectID: ids[i].Hex(), Quantity: grams, Unit: "g"}}
+	}
+	_, err = NewRecipeRepository(s).Insert(ctx, []model.RecipePostType{
+		{Name: "crepes", Servings: 2, Ingredients: line(0, 300)},    // 150
+		{Name: "broth", Servings: 1, Ingredients: line(0, 200)},     // 200
+		{Name: "fudge", Servings: 4, Ingredients: line(1, 100)},     // 125
+		{Name: "apple pie", Servings: 4, Ingredients: line(1, 120)}, // 150
+		{Name: "dumplings", Servings: 1, Ingredients: line(1, 40)},  // 200
+		{Name: "eclairs", Servings: 1, Ingredients: line(0, 100)},   // 100
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// pageAll follows the cursors of params two recipes at a time and returns the
+// names of all recipes listed.
+func pageAll(t *testing.T, repo *RecipeRepository, params ListParams) []string {
+	t.Helper()
+	params.Limit = 2
+	var names []string
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("paging does not end")
+		}
+		recipes, next, err := repo.Page(context.Background(), params)
+		if err != nil {
+			t.Fatalf("Page after %d pages: %v", pages, err)
+		}
+		for _, recipe := range recipes {
+			names = append(names, recipe.Name)
+		}
+		if next == "" {
+			return names
+		}
+		params.Cursor = next
+	}
+}
+
+func TestRecipePageCursorRoundTrip(t *testing.T) {
+	intp := func(n int) *int { return &n }
+	tests := []struct {
+		name   string
+		params ListParams
+		want   []string
+	}{
+		{"created", ListParams{}, []string{"crepes", "broth", "fudge", "apple pie", "dumplings", "eclairs"}},
+		{"created descending", ListParams{Sort: "-created"}, []string{"eclairs", "dumplings", "apple pie", "fudge", "broth", "crepes"}},
+		{"name", ListParams{Sort: "name"}, []string{"apple pie", "broth", "crepes", "dumplings", "eclairs", "fudge"}},
+		{"name descending", ListParams{Sort: "-name"}, []string{"fudge", "eclairs", "dumplings", "crepes", "broth", "apple pie"}},
+		{"name prefix", ListParams{Sort: "name", NamePrefix: "e"}, []string{"eclairs"}},
+		{"calories", ListParams{Sort: "calories"}, []string{"eclairs", "fudge", "crepes", "apple pie", "broth", "dumplings"}},
+		{"calories descending", ListParams{Sort: "-calories"}, []string{"dumplings", "broth", "apple pie", "crepes", "fudge", "eclairs"}},
+		{"calorie range", ListParams{Sort: "calories", MinCalories: intp(125), MaxCalories: intp(150)}, []string{"fudge", "crepes", "apple pie"}},
+		{"calorie filter by name", ListParams{Sort: "name", MinCalories: intp(200)}, []string{"broth", "dumplings"}},
+	}
+
+	repo := NewRecipeRepository(seedMenu(t))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pageAll(t, repo, tt.params); !slices.Equal(got, tt.want) {
+				t.Errorf("listed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecipePageInvalidCursor(t *testing.T) {
+	repo := NewRecipeRepository(seedMenu(t))
+	_, cursor, err := repo.Page(context.Background(), ListParams{Limit: 2, Sort: "calories"})
+	if err != nil || cursor == "" {
+		t.Fatalf("Page = %q, %v; want a cursor", cursor, err)
+	}
+
+	tests := []struct {
+		name   string
+		params ListParams
+	}{
+		{"not base64", ListParams{Sort: "calories", Cursor: "not a cursor!"}},
+		{"tampered", ListParams{Sort: "calories", Cursor: cursor[:len(cursor)-1] + "!"}},
+		{"truncated", ListParams{Sort: "calories", Cursor: cursor[:len(cursor)/2]}},
+		{"not JSON", ListParams{Sort: "calories", Cursor: base64.RawURLEncoding.EncodeToString([]byte("calories"))}},
+		{"invalid ID", ListParams{Sort: "calories", Cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"s":"calories","i":"nope"}`))}},
+		{"other sort", ListParams{Sort: "name", Cursor: cursor}},
+		{"other direction", ListParams{Sort: "-calories", Cursor: cursor}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := repo.Page(context.Background(), tt.params)
+			if !errors.Is(err, ErrInvalidListParams) {
+				t.Errorf("Page error = %v, want %v", err, ErrInvalidListParams)
+			}
+		})
+	}
+}
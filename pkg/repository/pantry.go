@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/store"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrUnknownIngredient is wrapped by the error Insert returns when pantry
+// items reference ingredients that do not exist.
+var ErrUnknownIngredient = errors.New("unknown ingredient")
+
+// PantryRepository handles database operations related to users' pantries.
+// Every method is scoped to one user; items of other users are reported as
+// not found. Deleting an ingredient leaves the items holding it in the store,
+// so they are hidden the same way.
+type PantryRepository struct {
+	store       store.PantryStore
+	ingredients store.IngredientStore
+}
+
+// NewPantryRepository creates a new PantryRepository.
+func NewPantryRepository(s store.Store) *PantryRepository {
+	return &PantryRepository{store: s.Pantry(), ingredients: s.Ingredients()}
+}
+
+// List returns the items of the user's pantry.
+func (r *PantryRepository) List(ctx context.Context, userID string) ([]model.PantryItem, error) {
+	items, err := r.store.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pantry: %w", err)
+	}
+	return r.withoutOrphans(ctx, items)
+}
+
+// Expiring returns the items of the user's pantry that expire within the
+// given duration from now, or have already expired, soonest first.
+func (r *PantryRepository) Expiring(ctx context.Context, userID string, within time.Duration) ([]model.PantryItem, error) {
+	items, err := r.store.ExpiringBefore(ctx, userID, time.Now().Add(within))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expiring pantry items: %w", err)
+	}
+	return r.withoutOrphans(ctx, items)
+}
+
+// withoutOrphans drops the items whose ingredient no longer exists, looking
+// the ingredients up in one query.
+func (r *PantryRepository) withoutOrphans(ctx context.Context, items []model.PantryItem) ([]model.PantryItem, error) {
+	if len(items) == 0 {
+		return items, nil
+	}
+	var objIDs []primitive.ObjectID
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if seen[item.IngredientID] {
+			continue
+		}
+		seen[item.IngredientID] = true
+		if objID, err := primitive.ObjectIDFromHex(item.IngredientID); err == nil {
+			objIDs = append(objIDs, objID)
+		}
+	}
+
+	found, err := r.ingredients.FindByIDs(ctx, objIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up ingredients: %w", err)
+	}
+	exists := make(map[string]bool, len(found))
+	for _, ingredient := range found {
+		exists[ingredient.ObjectID.Hex()] = true
+	}
+	kept := make([]model.PantryItem, 0, len(items))
+	for _, item := range items {
+		if exists[item.IngredientID] {
+			kept = append(kept, item)
+		}
+	}
+	return kept, nil
+}
+
+// FindByID finds an item of the user's pantry by its ID. The returned error
+// wraps store.ErrNotFound if the item does not exist, belongs to another user
+// or holds a deleted ingredient.
+func (r *PantryRepository) FindByID(ctx context.Context, userID, itemID string) (*model.PantryItem, error) {
+	objID, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pantry item ID: %w", err)
+	}
+
+	item, err := r.store.FindByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pantry item: %w", err)
+	}
+	if item.UserID != userID {
+		return nil, fmt.Errorf("failed to find pantry item: %w", store.ErrNotFound)
+	}
+	kept, err := r.withoutOrphans(ctx, []model.PantryItem{*item})
+	if err != nil {
+		return nil, err
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("failed to find pantry item: %w", store.ErrNotFound)
+	}
+	return item, nil
+}
+
+// Insert adds the given items to the user's pantry and returns their
+// generated IDs. Every item must reference an existing ingredient; otherwise
+// the returned error wraps ErrUnknownIngredient and nothing is written.
+func (r *PantryRepository) Insert(ctx context.Context, userID string, items []model.PantryItem) ([]primitive.ObjectID, error) {
+	var objIDs []primitive.ObjectID
+	for i, item := range items {
+		objID, err := primitive.ObjectIDFromHex(item.IngredientID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ingredient ID: %w", err)
+		}
+		objIDs = append(objIDs, objID)
+		items[i].IngredientID = objID.Hex()
+		items[i].UserID = userID
+	}
+
+	found, err := r.ingredients.FindByIDs(ctx, objIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up ingredients: %w", err)
+	}
+	exists := make(map[string]bool, len(found))
+	for _, ingredient := range found {
+		exists[ingredient.ObjectID.Hex()] = true
+	}
+	var unknown []string
+	for _, item := range items {
+		if !exists[item.IngredientID] {
+			unknown = append(unknown, item.IngredientID)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownIngredient, strings.Join(unknown, ", "))
+	}
+
+	ids, err := r.store.InsertMany(ctx, items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert pantry items: %w", err)
+	}
+	return ids, nil
+}
+
+// UpdateByID applies a partial update to an item of the user's pantry and
+// returns it as updated, or nil if there is no such item.
+func (r *PantryRepository) UpdateByID(ctx context.Context, userID, itemID string, updateData model.PantryItemUpdate) (*model.PantryItem, error) {
+	item, err := r.FindByID(ctx, userID, itemID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil // No item was found with the provided ID
+		}
+		return nil, err
+	}
+
+	updatedItem, err := r.store.UpdateByID(ctx, item.ObjectID, updateData)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil // Deleted concurrently
+		}
+		return nil, fmt.Errorf("failed to update pantry item: %w", err)
+	}
+	return updatedItem, nil
+}
+
+// DeleteByID deletes an item of the user's pantry and reports how many were
+// removed.
+func (r *PantryRepository) DeleteByID(ctx context.Context, userID, itemID string) (int64, error) {
+	item, err := r.FindByID(ctx, userID, itemID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return r.store.DeleteByID(ctx, item.ObjectID)
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/store"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPantryHidesDeletedIngredients(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	ingredientIDs, err := s.Ingredients().InsertMany(ctx, []model.Ingredient{{Name: "flour"}, {Name: "milk"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	soon := time.Now().Add(24 * time.Hour)
+	pantry := NewPantryRepository(s)
+	itemIDs, err := pantry.Insert(ctx, "ann", []model.PantryItem{
+		{IngredientID: ingredientIDs[0].Hex(), Quantity: 1, Unit: "kg", ExpiresAt: &soon},
+		{IngredientID: ingredientIDs[1].Hex(), Quantity: 1, Unit: "l", ExpiresAt: &soon},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := NewIngredientRepository(s).DeleteByName(ctx, "milk", 1, DeleteRestrict); err != nil {
+		t.Fatalf("DeleteByName: %v", err)
+	}
+
+	items, err := pantry.List(ctx, "ann")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].ObjectID != itemIDs[0] {
+		t.Errorf("List = %+v, want only the flour", items)
+	}
+	items, err = pantry.Expiring(ctx, "ann", 48*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].ObjectID != itemIDs[0] {
+		t.Errorf("Expiring = %+v, want only the flour", items)
+	}
+	if _, err := pantry.FindByID(ctx, "ann", itemIDs[1].Hex()); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("FindByID of the milk: got %v, want ErrNotFound", err)
+	}
+	quantity := 2.0
+	if item, err := pantry.UpdateByID(ctx, "ann", itemIDs[1].Hex(), model.PantryItemUpdate{Quantity: &quantity}); item != nil || err != nil {
+		t.Errorf("UpdateByID of the milk = %+v, %v; want no item", item, err)
+	}
+
+	// The item itself is left in the store.
+	if _, err := s.Pantry().FindByID(ctx, itemIDs[1]); err != nil {
+		t.Errorf("the milk was deleted from the store: %v", err)
+	}
+}
@@ -0,0 +1,326 @@
+package repository
+
+import (
+	"context"
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/nutrition"
+	"dynamicrecipes/pkg/store"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UnknownIngredients lists the ingredient references of one recipe that do
+// not match any stored ingredient.
+type UnknownIngredients struct {
+	// Index is the position of the recipe in the submitted batch.
+	Index      int
+	Name       string
+	UnknownIDs []string
+}
+
+// UnknownIngredientsError is returned by Insert and UpdateByID when recipes
+// reference ingredients that do not exist. Nothing is written in that case.
+type UnknownIngredientsError struct {
+	Recipes []UnknownIngredients
+}
+
+func (e *UnknownIngredientsError) Error() string {
+	return fmt.Sprintf("%d recipe(s) reference unknown ingredients", len(e.Recipes))
+}
+
+// RecipeRepository handles database operations related to recipes.
+type RecipeRepository struct {
+	store       store.RecipeStore
+	ingredients store.IngredientStore
+}
+
+// NewRecipeRepository creates a new RecipeRepository.
+func NewRecipeRepository(s store.Store) *RecipeRepository {
+	return &RecipeRepository{store: s.Recipes(), ingredients: s.Ingredients()}
+}
+
+// checkIngredientRefs verifies, with a single lookup for the whole batch, that
+// every ingredient referenced by the given recipes exists, and returns them
+// keyed by their hex ID.
+func (r *RecipeRepository) checkIngredientRefs(ctx context.Context, recipes []model.RecipePostType) (map[string]model.Ingredient, error) {
+	var objIDs []primitive.ObjectID
+	seen := make(map[string]bool)
+	for _, recipe := range recipes {
+		for _, line := range recipe.Ingredients {
+			if seen[line.ObjectID] {
+				continue
+			}
+			seen[line.ObjectID] = true
+			objID, err := primitive.ObjectIDFromHex(line.ObjectID)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ingredient ID: %w", err)
+			}
+			objIDs = append(objIDs, objID)
+		}
+	}
+	if len(objIDs) == 0 {
+		return nil, nil
+	}
+
+	found, err := r.ingredients.FindByIDs(ctx, objIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up ingredients: %w", err)
+	}
+	byID := make(map[string]model.Ingredient, len(found))
+	for _, ingredient := range found {
+		byID[ingredient.ObjectID.Hex()] = ingredient
+	}
+
+	var unknown []UnknownIngredients
+	for i, recipe := range recipes {
+		var missing []string
+		for _, line := range recipe.Ingredients {
+			if _, ok := byID[line.ObjectID]; !ok {
+				missing = append(missing, line.ObjectID)
+			}
+		}
+		if len(missing) > 0 {
+			unknown = append(unknown, UnknownIngredients{Index: i, Name: recipe.Name, UnknownIDs: missing})
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, &UnknownIngredientsError{Recipes: unknown}
+	}
+	return byID, nil
+}
+
+// FindByID finds a recipe by its ID.
+func (r *RecipeRepository) FindByID(ctx context.Context, recipeID string) (*model.RecipeReturnType, error) {
+	objID, err := primitive.ObjectIDFromHex(recipeID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipe ID: %w", err)
+	}
+
+	recipe, err := r.store.FindByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find recipe: %w", err)
+	}
+	return recipe, nil
+}
+
+// FindByIDs fetches the recipes with the given IDs in one query and returns
+// them keyed by their hex ID. Unknown IDs are absent from the map.
+func (r *RecipeRepository) FindByIDs(ctx context.Context, recipeIDs []string) (map[string]model.RecipeReturnType, error) {
+	objIDs := make([]primitive.ObjectID, 0, len(recipeIDs))
+	for _, recipeID := range recipeIDs {
+		objID, err := primitive.ObjectIDFromHex(recipeID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipe ID: %w", err)
+		}
+		objIDs = append(objIDs, objID)
+	}
+
+	recipes, err := r.store.FindByIDs(ctx, objIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find recipes: %w", err)
+	}
+
+	byID := make(map[string]model.RecipeReturnType, len(recipes))
+	for _, recipe := range recipes {
+		byID[recipe.ObjectID.Hex()] = recipe
+	}
+	return byID, nil
+}
+
+// List returns every recipe with its ingredient references unresolved.
+func (r *RecipeRepository) List(ctx context.Context) ([]model.RecipeReturnType, error) {
+	recipes, err := r.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recipes: %w", err)
+	}
+	return recipes, nil
+}
+
+// Page returns one page of recipes selected by params, along with the cursor
+// of the next page or "" on the last one. Recipes can be sorted by creation
+// time, name or calories per serving, and filtered by name prefix and calories
+// per serving. Errors caused by params wrap ErrInvalidListParams.
+func (r *RecipeRepository) Page(ctx context.Context, params ListParams) ([]model.RecipeReturnType, string, error) {
+	q, err := params.query(store.SortCreated, store.SortName, store.SortCalories)
+	if err != nil {
+		return nil, "", err
+	}
+
+	recipes, err := r.store.Query(ctx, q)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list recipes: %w", err)
+	}
+	recipes, next := nextPage(recipes, q, store.RecipePosition)
+	return recipes, next, nil
+}
+
+// RefreshCalories recomputes the stored calories per serving of every recipe
+// and writes those that changed. It fills them in for recipes stored before
+// they were kept, and repairs any an interrupted ingredient update missed.
+func (r *RecipeRepository) RefreshCalories(ctx context.Context) error {
+	recipes, err := r.store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list recipes: %w", err)
+	}
+	return refreshCalories(ctx, r.store, r.ingredients, recipes)
+}
+
+// refreshCalories recomputes the calories per serving of the given recipes
+// and stores those that differ from the stored value. Recipes deleted in the
+// meantime are skipped.
+func refreshCalories(ctx context.Context, recipes store.RecipeStore, ingredients store.IngredientStore, stale []model.RecipeReturnType) error {
+	found, err := loadIngredients(ctx, ingredients, stale)
+	if err != nil {
+		return err
+	}
+	for _, recipe := range stale {
+		calories := caloriesPerServing(recipe.ID, recipe.Servings, found)
+		if calories == recipe.CaloriesPerServing {
+			continue
+		}
+		if err := recipes.SetCalories(ctx, recipe.ObjectID, calories); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("failed to store recipe calories: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadIngredients fetches the ingredients referenced by the given recipes in
+// one query and returns them keyed by their hex ID.
+func loadIngredients(ctx context.Context, ingredients store.IngredientStore, recipes []model.RecipeReturnType) (map[string]model.Ingredient, error) {
+	var ids []primitive.ObjectID
+	seen := make(map[string]bool)
+	for _, recipe := range recipes {
+		for _, line := range recipe.ID {
+			if seen[line.ObjectID] {
+				continue
+			}
+			seen[line.ObjectID] = true
+			if id, err := primitive.ObjectIDFromHex(line.ObjectID); err == nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	found, err := ingredients.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up ingredients: %w", err)
+	}
+	byID := make(map[string]model.Ingredient, len(found))
+	for _, ingredient := range found {
+		byID[ingredient.ObjectID.Hex()] = ingredient
+	}
+	return byID, nil
+}
+
+// caloriesPerServing computes the calories per serving of a recipe with the
+// given lines, rounded to whole calories. Lines whose ingredient is not in
+// ingredients, because it no longer exists, count as zero.
+func caloriesPerServing(lines []model.IngredientIDType, servings int, ingredients map[string]model.Ingredient) int {
+	var resolved []model.RecipeIngredient
+	for _, line := range lines {
+		if ingredient, ok := ingredients[line.ObjectID]; ok {
+			resolved = append(resolved, model.RecipeIngredient{Ingredient: ingredient, Quantity: line.Quantity, Unit: line.Unit})
+		}
+	}
+	return int(math.Round(nutrition.Compute(resolved, servings).PerServing.Calories))
+}
+
+// Insert stores the given recipes, with their calories per serving, and
+// returns their generated IDs. It fails with an *UnknownIngredientsError if
+// any recipe references a missing ingredient.
+func (r *RecipeRepository) Insert(ctx context.Context, recipes []model.RecipePostType) ([]primitive.ObjectID, error) {
+	ingredients, err := r.checkIngredientRefs(ctx, recipes)
+	if err != nil {
+		return nil, err
+	}
+	recipes = slices.Clone(recipes)
+	for i, recipe := range recipes {
+		recipes[i].CaloriesPerServing = caloriesPerServing(recipe.Ingredients, recipe.Servings, ingredients)
+	}
+
+	ids, err := r.store.InsertMany(ctx, recipes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert recipes: %w", err)
+	}
+	return ids, nil
+}
+
+// UpdateByID applies a partial update to the recipe identified by its ID and
+// returns the updated recipe. New ingredient lines are checked like in Insert,
+// and the calories per serving are recomputed when the lines or servings
+// change. The recipe must still be at version; otherwise the returned error
+// wraps store.ErrVersionConflict.
+func (r *RecipeRepository) UpdateByID(ctx context.Context, recipeID string, version int64, updateData model.RecipeUpdate) (*model.RecipeReturnType, error) {
+	objID, err := primitive.ObjectIDFromHex(recipeID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipe ID: %w", err)
+	}
+
+	if updateData.Ingredients != nil {
+		var name string
+		if updateData.Name != nil {
+			name = *updateData.Name
+		}
+		check := []model.RecipePostType{{Name: name, Ingredients: *updateData.Ingredients}}
+		if _, err := r.checkIngredientRefs(ctx, check); err != nil {
+			return nil, err
+		}
+	}
+	if updateData.Ingredients != nil || updateData.Servings != nil {
+		current, err := r.store.FindByID(ctx, objID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to find recipe: %w", err)
+		}
+		if current.Version != version {
+			return nil, fmt.Errorf("failed to update recipe: %w", store.ErrVersionConflict)
+		}
+		// The update below is made at the same version, so the calories
+		// match the lines and servings it stores.
+		updateData.Apply(current)
+		ingredients, err := loadIngredients(ctx, r.ingredients, []model.RecipeReturnType{*current})
+		if err != nil {
+			return nil, err
+		}
+		calories := caloriesPerServing(current.ID, current.Servings, ingredients)
+		updateData.CaloriesPerServing = &calories
+	}
+
+	updatedRecipe, err := r.store.UpdateByID(ctx, objID, version, updateData)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil // No document was found with the provided ID
+		}
+		return nil, fmt.Errorf("failed to update recipe: %w", err)
+	}
+
+	return updatedRecipe, nil
+}
+
+// DeleteByID deletes the recipe with the given ID and reports how many were
+// removed. The recipe must still be at version; otherwise the returned error
+// wraps store.ErrVersionConflict.
+func (r *RecipeRepository) DeleteByID(ctx context.Context, recipeID string, version int64) (int64, error) {
+	objID, err := primitive.ObjectIDFromHex(recipeID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid recipe ID: %w", err)
+	}
+
+	if err := r.store.DeleteByID(ctx, objID, version); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return 0, nil // No document was found with the provided ID
+		}
+		return 0, fmt.Errorf("failed to delete recipe: %w", err)
+	}
+	return 1, nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/store"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestRecipeCaloriesFollowChanges(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	ingredientIDs, err := s.Ingredients().InsertMany(ctx, []model.Ingredient{
+		{Name: "flour", Calories: 4},
+		{Name: "butter", Calories: 7},
+		{Name: "milk", Calories: 1, Density: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := func(i int, q float64, unit string) model.IngredientIDType {
+		return model.IngredientIDType{ObjectID: ingredientIDs[i].Hex(), Quantity: q, Unit: unit}
+	}
+	ingredients := NewIngredientRepository(s)
+	recipes := NewRecipeRepository(s)
+
+	recipeIDs, err := recipes.Insert(ctx, []model.RecipePostType{
+		{Name: "bread", Servings: 4, Ingredients: []model.IngredientIDType{line(0, 500, "g")}},
+		{Name: "shortbread", Servings: 2, Ingredients: []model.IngredientIDType{line(0, 100, "g"), line(1, 50, "g")}},
+		{Name: "porridge", Servings: 1, Ingredients: []model.IngredientIDType{line(2, 250, "ml")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bread, porridge := recipeIDs[0].Hex(), recipeIDs[2].Hex()
+
+	// check lists the recipes by calories per serving, ties by creation.
+	check := func(step string, want ...string) {
+		t.Helper()
+		page, _, err := recipes.Page(ctx, ListParams{Sort: "calories"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, recipe := range page {
+			got = append(got, fmt.Sprint(recipe.Name, " ", recipe.CaloriesPerServing))
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: listed %q, want %q", step, got, want)
+		}
+	}
+	check("inserted", "porridge 250", "shortbread 375", "bread 500")
+
+	calories := 2
+	if _, err := ingredients.UpdateByID(ctx, ingredientIDs[0].Hex(), 1, model.IngredientUpdate{Calories: &calories}); err != nil {
+		t.Fatal(err)
+	}
+	check("flour updated", "bread 250", "porridge 250", "shortbread 275")
+
+	servings := 1
+	if _, err := recipes.UpdateByID(ctx, bread, 1, model.RecipeUpdate{Servings: &servings}); err != nil {
+		t.Fatal(err)
+	}
+	lines := []model.IngredientIDType{line(2, 500, "ml")}
+	if _, err := recipes.UpdateByID(ctx, porridge, 1, model.RecipeUpdate{Ingredients: &lines}); err != nil {
+		t.Fatal(err)
+	}
+	check("recipes updated", "shortbread 275", "porridge 500", "bread 1000")
+
+	if _, _, err := ingredients.DeleteByName(ctx, "butter", 1, DeleteDetach); err != nil {
+		t.Fatal(err)
+	}
+	check("butter detached", "shortbread 100", "porridge 500", "bread 1000")
+
+	// Recipes stored before calories were kept are filled in by a refresh.
+	if err := s.Recipes().SetCalories(ctx, recipeIDs[1], 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := recipes.RefreshCalories(ctx); err != nil {
+		t.Fatal(err)
+	}
+	check("refreshed", "shortbread 100", "porridge 500", "bread 1000")
+}
@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"context"
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/store"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotListOwner is returned by changes to a shopping list that only its
+// owner may make: deleting it and changing its members.
+var ErrNotListOwner = errors.New("only the owner of a shopping list may do this")
+
+// ShoppingListRepository handles database operations related to shared
+// shopping lists. A list is visible to its owner and its members; to anyone
+// else it is reported as not found.
+type ShoppingListRepository struct {
+	store       store.ShoppingListStore
+	ingredients store.IngredientStore
+}
+
+// NewShoppingListRepository creates a new ShoppingListRepository.
+func NewShoppingListRepository(s store.Store) *ShoppingListRepository {
+	return &ShoppingListRepository{store: s.ShoppingLists(), ingredients: s.Ingredients()}
+}
+
+// canAccess reports whether the user owns or is a member of list.
+func canAccess(list *model.ShoppingList, userID string) bool {
+	if list.OwnerID == userID {
+		return true
+	}
+	for _, member := range list.Members {
+		if member == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// members returns the distinct member IDs, without the owner and blanks.
+func members(ownerID string, userIDs []string) []string {
+	result := make([]string, 0, len(userIDs))
+	seen := map[string]bool{ownerID: true}
+	for _, userID := range userIDs {
+		userID = strings.TrimSpace(userID)
+		if userID == "" || seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		result = append(result, userID)
+	}
+	return result
+}
+
+// List returns the lists the user owns or is a member of.
+func (r *ShoppingListRepository) List(ctx context.Context, userID string) ([]model.ShoppingList, error) {
+	lists, err := r.store.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shopping lists: %w", err)
+	}
+	return lists, nil
+}
+
+// FindByID finds a list the user has access to. The returned error wraps
+// store.ErrNotFound if the list does not exist or the user is neither its
+// owner nor a member.
+func (r *ShoppingListRepository) FindByID(ctx context.Context, userID, listID string) (*model.ShoppingList, error) {
+	objID, err := primitive.ObjectIDFromHex(listID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shopping list ID: %w", err)
+	}
+
+	list, err := r.store.FindByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find shopping list: %w", err)
+	}
+	if !canAccess(list, userID) {
+		return nil, fmt.Errorf("failed to find shopping list: %w", store.ErrNotFound)
+	}
+	return list, nil
+}
+
+// Create stores a new list owned by the user and returns it with its
+// generated IDs.
+func (r *ShoppingListRepository) Create(ctx context.Context, userID string, list model.ShoppingList) (*model.ShoppingList, error) {
+	list.OwnerID = userID
+	list.Members = members(userID, list.Members)
+	if err := r.resolveItems(ctx, list.Items); err != nil {
+		return nil, err
+	}
+
+	created, err := r.store.Insert(ctx, list)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert shopping list: %w", err)
+	}
+	return created, nil
+}
+
+// resolveItems checks that the items referencing ingredients reference
+// existing ones, and fills in their name and category where not given. The
+// returned error wraps ErrUnknownIngredient if any does not exist.
+func (r *ShoppingListRepository) resolveItems(ctx context.Context, items []model.ShoppingListItem) error {
+	var objIDs []primitive.ObjectID
+	for i, item := range items {
+		if item.IngredientID == "" {
+			continue
+		}
+		objID, err := primitive.ObjectIDFromHex(item.IngredientID)
+		if err != nil {
+			return fmt.Errorf("invalid ingredient ID: %w", err)
+		}
+		objIDs = append(objIDs, objID)
+		items[i].IngredientID = objID.Hex()
+	}
+	if len(objIDs) == 0 {
+		return nil
+	}
+
+	found, err := r.ingredients.FindByIDs(ctx, objIDs)
+	if err != nil {
+		return fmt.Errorf("failed to look up ingredients: %w", err)
+	}
+	byID := make(map[string]model.Ingredient, len(found))
+	for _, ingredient := range found {
+		byID[ingredient.ObjectID.Hex()] = ingredient
+	}
+	var unknown []string
+	for i, item := range items {
+		if item.IngredientID == "" {
+			continue
+		}
+		ingredient, ok := byID[item.IngredientID]
+		if !ok {
+			unknown = append(unknown, item.IngredientID)
+			continue
+		}
+		if item.Name == "" {
+			items[i].Name = ingredient.Name
+		}
+		if item.Category == "" {
+			items[i].Category = strings.ToLower(ingredient.Category)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownIngredient, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// UpdateByID applies a partial update to a list the user has access to and
+// returns it as updated, or nil if there is no such list. Only the owner may
+// change the members; others get ErrNotListOwner.
+func (r *ShoppingListRepository) UpdateByID(ctx context.Context, userID, listID string, updateData model.ShoppingListUpdate) (*model.ShoppingList, error) {
+	list, err := r.FindByID(ctx, userID, listID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil // No list was found with the provided ID
+		}
+		return nil, err
+	}
+	if updateData.Members != nil {
+		if list.OwnerID != userID {
+			return nil, ErrNotListOwner
+		}
+		normalized := members(list.OwnerID, *updateData.Members)
+		updateData.Members = &normalized
+	}
+
+	return r.modified(r.store.UpdateByID(ctx, list.ObjectID, updateData))
+}
+
+// modified translates the result of a store change to a list for the
+// repository's callers: not found becomes nil.
+func (r *ShoppingListRepository) modified(list *model.ShoppingList, err error) (*model.ShoppingList, error) {
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil // Deleted concurrently
+		}
+		return nil, fmt.Errorf("failed to update shopping list: %w", err)
+	}
+	return list, nil
+}
+
+// DeleteByID deletes a list owned by the user and returns it as it was, or
+// nil if there is no such list. Members get ErrNotListOwner.
+func (r *ShoppingListRepository) DeleteByID(ctx context.Context, userID, listID string) (*model.ShoppingList, error) {
+	list, err := r.FindByID(ctx, userID, listID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if list.OwnerID != userID {
+		return nil, ErrNotListOwner
+	}
+
+	deleted, err := r.store.DeleteByID(ctx, list.ObjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete shopping list: %w", err)
+	}
+	if deleted == 0 {
+		return nil, nil
+	}
+	return list, nil
+}
+
+// AddItems appends items to a list the user has access to and returns the
+// updated list, or nil if there is no such list. Items are resolved as by
+// Create.
+func (r *ShoppingListRepository) AddItems(ctx context.Context, userID, listID string, items []model.ShoppingListItem) (*model.ShoppingList, error) {
+	list, err := r.FindByID(ctx, userID, listID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := r.resolveItems(ctx, items); err != nil {
+		return nil, err
+	}
+
+	return r.modified(r.store.AddItems(ctx, list.ObjectID, items))
+}
+
+// UpdateItem applies a partial update, such as checking it off, to an item of
+// a list the user has access to and returns the updated list, or nil if there
+// is no such list or item.
+func (r *ShoppingListRepository) UpdateItem(ctx context.Context, userID, listID, itemID string, updateData model.ShoppingListItemUpdate) (*model.ShoppingList, error) {
+	list, itemObjID, err := r.findItem(ctx, userID, listID, itemID)
+	if list == nil || err != nil {
+		return nil, err
+	}
+
+	return r.modified(r.store.UpdateItem(ctx, list.ObjectID, itemObjID, updateData))
+}
+
+// RemoveItem removes an item from a list the user has access to and returns
+// the updated list, or nil if there is no such list or item.
+func (r *ShoppingListRepository) RemoveItem(ctx context.Context, userID, listID, itemID string) (*model.ShoppingList, error) {
+	list, itemObjID, err := r.findItem(ctx, userID, listID, itemID)
+	if list == nil || err != nil {
+		return nil, err
+	}
+
+	return r.modified(r.store.RemoveItem(ctx, list.ObjectID, itemObjID))
+}
+
+// findItem returns the list the user has access to and the parsed item ID,
+// or a nil list if there is no such list.
+func (r *ShoppingListRepository) findItem(ctx context.Context, userID, listID, itemID string) (*model.ShoppingList, primitive.ObjectID, error) {
+	itemObjID, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return nil, primitive.NilObjectID, fmt.Errorf("invalid shopping list item ID: %w", err)
+	}
+
+	list, err := r.FindByID(ctx, userID, listID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, primitive.NilObjectID, nil
+		}
+		return nil, primitive.NilObjectID, err
+	}
+	return list, itemObjID, nil
+}
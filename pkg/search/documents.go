@@ -0,0 +1,18 @@
+package search
+
+import (
+	"dynamicrecipes/pkg/model"
+	"strings"
+)
+
+// IngredientDocument returns the searchable text of an ingredient.
+func IngredientDocument(ingredient model.Ingredient) Document {
+	return Document{Kind: KindIngredient, ID: ingredient.ObjectID.Hex(), Name: ingredient.Name}
+}
+
+// RecipeDocument returns the searchable text of a stored recipe: its name,
+// and its instructions and notes as free text.
+func RecipeDocument(recipe model.RecipeReturnType) Document {
+	text := strings.Join(append(append([]string(nil), recipe.Instructions...), recipe.Notes), "\n")
+	return Document{Kind: KindRecipe, ID: recipe.ObjectID.Hex(), Name: recipe.Name, Text: text}
+}
@@ -0,0 +1,299 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Kind says what a Document describes.
+type Kind string
+
+const (
+	KindRecipe     Kind = "recipe"
+	KindIngredient Kind = "ingredient"
+)
+
+// Document is the searchable text of one recipe or ingredient.
+type Document struct {
+	Kind Kind
+	ID   string
+	Name string
+	Tags []string
+	// Text is free text such as instructions; it weighs least in the ranking.
+	Text string
+}
+
+// Result is a matching document and its relevance; higher is better.
+type Result struct {
+	Kind  Kind
+	ID    string
+	Name  string
+	Score float64
+}
+
+// Field weights: a hit in the name counts more than one in the tags, which
+// counts more than one in the text.
+const (
+	nameWeight = 3
+	tagWeight  = 2
+	textWeight = 1
+)
+
+type docKey struct {
+	kind Kind
+	id   string
+}
+
+type entry struct {
+	doc   Document
+	terms []string
+}
+
+// Index is an in-memory inverted index over Documents. It is safe for
+// concurrent use.
+type Index struct {
+	mu   sync.RWMutex
+	docs map[docKey]*entry
+	// postings maps a term to the documents containing it, with the weight
+	// of the best field it occurs in.
+	postings map[string]map[docKey]float64
+}
+
+// New creates an empty index.
+func New() *Index {
+	return &Index{
+		docs:     make(map[docKey]*entry),
+		postings: make(map[string]map[docKey]float64),
+	}
+}
+
+// Put adds doc to the index, replacing any document of the same kind and ID.
+func (x *Index) Put(doc Document) {
+	weights := make(map[string]float64)
+	add := func(text string, weight float64) {
+		for _, term := range Tokenize(text) {
+			if weight > weights[term] {
+				weights[term] = weight
+			}
+		}
+	}
+	// Spread the name weight over its words, so that "pancakes" ranks above
+	// "buttermilk pancakes" for the query "pancakes".
+	if n := len(Tokenize(doc.Name)); n > 0 {
+		add(doc.Name, nameWeight/math.Sqrt(float64(n)))
+	}
+	for _, tag := range doc.Tags {
+		add(tag, tagWeight)
+	}
+	add(doc.Text, textWeight)
+
+	key := docKey{doc.Kind, doc.ID}
+	e := &entry{doc: doc, terms: make([]string, 0, len(weights))}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.remove(key)
+	for term, weight := range weights {
+		docs, ok := x.postings[term]
+		if !ok {
+			docs = make(map[docKey]float64)
+			x.postings[term] = docs
+		}
+		docs[key] = weight
+		e.terms = append(e.terms, term)
+	}
+	x.docs[key] = e
+}
+
+// Remove drops the document of the given kind and ID, if indexed.
+func (x *Index) Remove(kind Kind, id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.remove(docKey{kind, id})
+}
+
+func (x *Index) remove(key docKey) {
+	e, ok := x.docs[key]
+	if !ok {
+		return
+	}
+	for _, term := range e.terms {
+		delete(x.postings[term], key)
+		if len(x.postings[term]) == 0 {
+			delete(x.postings, term)
+		}
+	}
+	delete(x.docs, key)
+}
+
+// Len returns the number of indexed documents.
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.docs)
+}
+
+// Search returns up to limit documents matching query, best first. Each query
+// term matches index terms exactly, by prefix, or within a small edit
+// distance, so "pancak" and "pancaeks" both find "pancakes". Documents
+// matching more of the query terms rank higher. An empty kind searches all
+// kinds.
+//
+// Every query term is compared with every indexed term, so a search costs
+// O(query terms × vocabulary). Each comparison is cheap, as the edit distance
+// gives up once it exceeds the allowed typos, which is fast enough for the
+// names and instructions of a recipe collection. Terms cannot simply be
+// bucketed by length, since a query also matches prefixes of longer terms.
+func (x *Index) Search(query string, kind Kind, limit int) []Result {
+	queryTerms := Tokenize(query)
+	if len(queryTerms) == 0 || limit <= 0 {
+		return []Result{}
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	scores := make(map[docKey]float64)
+	matched := make(map[docKey]int)
+	for _, queryTerm := range queryTerms {
+		// Best score of this query term per document.
+		best := make(map[docKey]float64)
+		for term, docs := range x.postings {
+			quality := matchQuality(queryTerm, term)
+			if quality == 0 {
+				continue
+			}
+			idf := math.Log(1 + float64(len(x.docs))/float64(len(docs)))
+			for key, weight := range docs {
+				if kind != "" && key.kind != kind {
+					continue
+				}
+				if score := quality * weight * idf; score > best[key] {
+					best[key] = score
+				}
+			}
+		}
+		for key, score := range best {
+			scores[key] += score
+			matched[key]++
+		}
+	}
+
+	normalized := strings.Join(queryTerms, " ")
+	results := make([]Result, 0, len(scores))
+	for key, score := range scores {
+		doc := x.docs[key].doc
+		// Favour documents matching every term, then exact and leading
+		// name matches.
+		score *= float64(matched[key]) / float64(len(queryTerms))
+		name := strings.Join(Tokenize(doc.Name), " ")
+		switch {
+		case name == normalized:
+			score *= 2
+		case strings.HasPrefix(name, normalized):
+			score *= 1.5
+		}
+		results = append(results, Result{Kind: doc.Kind, ID: doc.ID, Name: doc.Name, Score: math.Round(score*1000) / 1000})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// Tokenize lowercases text and splits it into words of letters and digits.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matchQuality rates how well an index term matches a query term, from 1 for
+// an exact match down to 0 for no match.
+func matchQuality(queryTerm, term string) float64 {
+	if queryTerm == term {
+		return 1
+	}
+	q, t := []rune(queryTerm), []rune(term)
+	if len(q) >= 2 && strings.HasPrefix(term, queryTerm) {
+		return 0.5 + 0.4*float64(len(q))/float64(len(t))
+	}
+
+	// Allow one typo from four letters on and two from eight on.
+	maxEdits := 0
+	switch {
+	case len(q) >= 8:
+		maxEdits = 2
+	case len(q) >= 4:
+		maxEdits = 1
+	}
+	if maxEdits == 0 {
+		return 0
+	}
+	full, prefix := distance(q, t, maxEdits)
+	switch {
+	case full <= maxEdits:
+		return 0.6 - 0.2*float64(full-1)
+	case len(q) >= 5 && prefix <= maxEdits:
+		// A typo in the beginning of a longer term, e.g. "pancaek" for
+		// "pancakes".
+		return 0.4 - 0.1*float64(prefix-1)
+	}
+	return 0
+}
+
+// distance returns the optimal string alignment distance (insertions,
+// deletions, substitutions and swaps of adjacent letters) between a and b, and
+// the smallest distance between a and a prefix of b. Both are maxEdits+1 once
+// they are known to exceed maxEdits.
+func distance(a, b []rune, maxEdits int) (full, prefix int) {
+	if len(a)-len(b) > maxEdits {
+		return maxEdits + 1, maxEdits + 1
+	}
+	// rows[0..2] are rows i-2, i-1 and i of the edit matrix.
+	rows := [3][]int{make([]int, len(b)+1), make([]int, len(b)+1), make([]int, len(b)+1)}
+	for j := range rows[1] {
+		rows[1][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		prev2, prev, cur := rows[0], rows[1], rows[2]
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > maxEdits {
+			return maxEdits + 1, maxEdits + 1
+		}
+		rows[0], rows[1], rows[2] = prev, cur, prev2
+	}
+
+	last := rows[1]
+	full, prefix = last[len(b)], last[len(b)]
+	for _, d := range last[1:] {
+		prefix = min(prefix, d)
+	}
+	return min(full, maxEdits+1), min(prefix, maxEdits+1)
+}
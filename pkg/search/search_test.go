@@ -0,0 +1,131 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatchQuality(t *testing.T) {
+	tests := []struct {
+		query, term string
+		want        bool
+	}{
+		{"egg", "egg", true},
+		{"eg", "egg", true},      // prefix
+		{"e", "eggs", false},     // too short for a prefix
+		{"egs", "egg", false},    // no typos below four letters
+		{"flor", "flour", true},  // one typo from four letters
+		{"flr", "flour", false},  // no typos below four letters
+		{"sugra", "sugar", true}, // a swap counts as one typo
+		{"sguar", "sugar", true},
+		{"sgura", "sugar", false}, // two typos below eight letters
+		{"buttre", "butter", true},
+		{"bttre", "butter", false},
+		{"pnacaeks", "pancakes", true},  // two typos from eight letters
+		{"pnaceaks", "pancakes", false}, // three typos
+		{"pancaek", "pancakes", true},   // a typo in a prefix of a longer term
+		{"pancek", "pancakes", true},
+		{"panxek", "pancakes", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query+" "+tt.term, func(t *testing.T) {
+			if got := matchQuality(tt.query, tt.term); (got > 0) != tt.want {
+				t.Errorf("matchQuality(%q, %q) = %g, want a match: %v", tt.query, tt.term, got, tt.want)
+			}
+		})
+	}
+
+	// Exact matches beat prefixes, which beat typos.
+	exact, prefix, typo := matchQuality("pancakes", "pancakes"), matchQuality("pancake", "pancakes"), matchQuality("pancaeks", "pancakes")
+	if !(exact > prefix && prefix > typo) {
+		t.Errorf("exact %g, prefix %g and typo %g are not in decreasing order", exact, prefix, typo)
+	}
+}
+
+// names returns the names of results, in order.
+func names(results []Result) []string {
+	out := make([]string, 0, len(results))
+	for _, result := range results {
+		out = append(out, result.Name)
+	}
+	return out
+}
+
+func TestSearchRanking(t *testing.T) {
+	x := New()
+	x.Put(Document{Kind: KindRecipe, ID: "1", Name: "Buttermilk pancakes"})
+	x.Put(Document{Kind: KindRecipe, ID: "2", Name: "Pancakes"})
+	x.Put(Document{Kind: KindRecipe, ID: "3", Name: "Potato pancake"})
+	x.Put(Document{Kind: KindRecipe, ID: "4", Name: "Crepes", Text: "Thinner than a pancake"})
+	x.Put(Document{Kind: KindIngredient, ID: "5", Name: "Pancetta"})
+	x.Put(Document{Kind: KindIngredient, ID: "6", Name: "Flour"})
+
+	tests := []struct {
+		name  string
+		query string
+		kind  Kind
+		limit int
+		want  []string
+	}{
+		{"exact before fuzzy", "pancakes", "", 10, []string{"Pancakes", "Buttermilk pancakes", "Potato pancake", "Crepes"}},
+		{"leading name prefix first", "panc", "", 10, []string{"Pancetta", "Pancakes", "Potato pancake", "Buttermilk pancakes", "Crepes"}},
+		{"typo", "pancaeks", "", 10, []string{"Pancakes", "Buttermilk pancakes", "Potato pancake", "Crepes"}},
+		{"every term matched first", "potato pancakes", "", 10, []string{"Potato pancake", "Pancakes", "Buttermilk pancakes", "Crepes"}},
+		{"kind", "panc", KindIngredient, 10, []string{"Pancetta"}},
+		{"limit", "pancakes", "", 2, []string{"Pancakes", "Buttermilk pancakes"}},
+		{"no match", "waffles", "", 10, []string{}},
+		{"too far off", "pnaceaks", "", 10, []string{}},
+		{"empty query", " ", "", 10, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := names(x.Search(tt.query, tt.kind, tt.limit)); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexUpdates(t *testing.T) {
+	x := New()
+	x.Put(Document{Kind: KindRecipe, ID: "1", Name: "Pancakes"})
+	x.Put(Document{Kind: KindIngredient, ID: "1", Name: "Flour"})
+
+	search := func(query string) []string {
+		t.Helper()
+		return names(x.Search(query, "", 10))
+	}
+	if got := search("pancakes"); !slices.Equal(got, []string{"Pancakes"}) {
+		t.Errorf("after insert: %q", got)
+	}
+
+	// Replacing a document drops its old terms.
+	x.Put(Document{Kind: KindRecipe, ID: "1", Name: "Waffles", Text: "Like pancakes, but crisp"})
+	if got := search("waffles"); !slices.Equal(got, []string{"Waffles"}) {
+		t.Errorf("after update, new name: %q", got)
+	}
+	if got := x.Search("pancakes", "", 10); len(got) != 1 || got[0].Score >= x.Search("waffles", "", 10)[0].Score {
+		t.Errorf("after update, old name still ranks like a name: %+v", got)
+	}
+	x.Put(Document{Kind: KindRecipe, ID: "1", Name: "Waffles"})
+	if got := search("pancakes"); len(got) != 0 {
+		t.Errorf("after update, old name: %q", got)
+	}
+	if x.Len() != 2 {
+		t.Errorf("Len() = %d after updates, want 2", x.Len())
+	}
+
+	// Documents of different kinds with the same ID are separate.
+	x.Remove(KindRecipe, "1")
+	if got := search("waffles"); len(got) != 0 {
+		t.Errorf("after delete: %q", got)
+	}
+	if got := search("flour"); !slices.Equal(got, []string{"Flour"}) {
+		t.Errorf("after deleting a recipe with the same ID: %q", got)
+	}
+	x.Remove(KindIngredient, "1")
+	x.Remove(KindIngredient, "1")
+	if x.Len() != 0 || len(x.postings) != 0 {
+		t.Errorf("%d documents and %d terms left after deleting everything", x.Len(), len(x.postings))
+	}
+}
@@ -0,0 +1,188 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Suggestion is an ingredient offered for a partially typed name.
+type Suggestion struct {
+	ID   string
+	Name string
+}
+
+// match tiers, best first.
+const (
+	tierNamePrefix = iota // the name starts with the query
+	tierWordPrefix        // every query word starts a word of the name
+	tierInfix             // some query word only occurs inside a word
+)
+
+// trieNode is a node of a trie over the suffixes of every word of every name.
+// ids holds the names whose words pass through the node, each with whether
+// it was reached from the start of a word.
+type trieNode struct {
+	children map[rune]*trieNode
+	ids      map[string]bool
+}
+
+// Suggester completes names from a prefix or a fragment of any of their
+// words. It is safe for concurrent use.
+type Suggester struct {
+	mu    sync.RWMutex
+	root  *trieNode
+	names map[string]string
+}
+
+// NewSuggester creates an empty Suggester.
+func NewSuggester() *Suggester {
+	return &Suggester{root: &trieNode{}, names: make(map[string]string)}
+}
+
+// Put adds or renames the entry with the given ID.
+func (s *Suggester) Put(id, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.names[id]; ok {
+		s.remove(id, old)
+	}
+	s.names[id] = name
+	for _, word := range Tokenize(name) {
+		runes := []rune(word)
+		for start := range runes {
+			node := s.root
+			for _, r := range runes[start:] {
+				child, ok := node.children[r]
+				if !ok {
+					child = &trieNode{}
+					if node.children == nil {
+						node.children = make(map[rune]*trieNode)
+					}
+					node.children[r] = child
+				}
+				node = child
+				if child.ids == nil {
+					child.ids = make(map[string]bool)
+				}
+				child.ids[id] = child.ids[id] || start == 0
+			}
+		}
+	}
+}
+
+// Remove drops the entry with the given ID, if present.
+func (s *Suggester) Remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name, ok := s.names[id]; ok {
+		s.remove(id, name)
+		delete(s.names, id)
+	}
+}
+
+func (s *Suggester) remove(id, name string) {
+	for _, word := range Tokenize(name) {
+		runes := []rune(word)
+		for start := range runes {
+			removePath(s.root, runes[start:], id)
+		}
+	}
+}
+
+// removePath drops id from the nodes along path and prunes the nodes left
+// empty.
+func removePath(node *trieNode, path []rune, id string) {
+	if len(path) == 0 {
+		return
+	}
+	child, ok := node.children[path[0]]
+	if !ok {
+		return
+	}
+	removePath(child, path[1:], id)
+	delete(child.ids, id)
+	if len(child.ids) == 0 {
+		delete(node.children, path[0])
+	}
+}
+
+// Suggest returns up to limit entries matching query: names starting with it
+// first, then names with words starting with each query word, then names
+// containing each query word anywhere. Shorter names come first within a
+// tier.
+func (s *Suggester) Suggest(query string, limit int) []Suggestion {
+	words := Tokenize(query)
+	if len(words) == 0 || limit <= 0 {
+		return []Suggestion{}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Intersect the names matching each query word, remembering whether all
+	// of them matched at a word start.
+	var candidates map[string]bool
+	for _, word := range words {
+		node := s.root
+		for _, r := range word {
+			if node = node.children[r]; node == nil {
+				return []Suggestion{}
+			}
+		}
+		if candidates == nil {
+			candidates = make(map[string]bool, len(node.ids))
+			for id, atStart := range node.ids {
+				candidates[id] = atStart
+			}
+			continue
+		}
+		for id, atStart := range candidates {
+			if wordStart, ok := node.ids[id]; ok {
+				candidates[id] = atStart && wordStart
+			} else {
+				delete(candidates, id)
+			}
+		}
+	}
+
+	prefix := strings.Join(words, " ")
+	type ranked struct {
+		Suggestion
+		tier int
+	}
+	results := make([]ranked, 0, len(candidates))
+	for id, atStart := range candidates {
+		name := s.names[id]
+		tier := tierInfix
+		switch {
+		case strings.HasPrefix(strings.Join(Tokenize(name), " "), prefix):
+			tier = tierNamePrefix
+		case atStart:
+			tier = tierWordPrefix
+		}
+		results = append(results, ranked{Suggestion{ID: id, Name: name}, tier})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.tier != b.tier {
+			return a.tier < b.tier
+		}
+		if len(a.Name) != len(b.Name) {
+			return len(a.Name) < len(b.Name)
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	suggestions := make([]Suggestion, len(results))
+	for i, result := range results {
+		suggestions[i] = result.Suggestion
+	}
+	return suggestions
+}
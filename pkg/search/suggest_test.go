@@ -0,0 +1,92 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+// suggested returns the names of suggestions, in order.
+func suggested(suggestions []Suggestion) []string {
+	out := make([]string, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		out = append(out, suggestion.Name)
+	}
+	return out
+}
+
+func TestSuggest(t *testing.T) {
+	s := NewSuggester()
+	for id, name := range map[string]string{
+		"1": "Butter",
+		"2": "Peanut butter",
+		"3": "Buttermilk",
+		"4": "Unsalted butter",
+		"5": "Brown sugar",
+		"6": "Sugar",
+		"7": "Butternut squash",
+		"8": "Sea salt",
+	} {
+		s.Put(id, name)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		limit int
+		want  []string
+	}{
+		{"name prefix before word prefix, shorter first", "butt", 10, []string{"Butter", "Buttermilk", "Butternut squash", "Peanut butter", "Unsalted butter"}},
+		{"whole word", "sugar", 10, []string{"Sugar", "Brown sugar"}},
+		{"infix", "gar", 10, []string{"Sugar", "Brown sugar"}},
+		{"word prefix before infix", "sa", 10, []string{"Sea salt", "Unsalted butter"}},
+		{"every query word must match", "butter pea", 10, []string{"Peanut butter"}},
+		{"query words in any order", "salt butt", 10, []string{"Unsalted butter"}},
+		{"case and punctuation", "  BROWN-su", 10, []string{"Brown sugar"}},
+		{"limit", "butt", 2, []string{"Butter", "Buttermilk"}},
+		{"no match", "flour", 10, []string{}},
+		{"no words", "--", 10, []string{}},
+		{"no limit", "butt", 0, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := suggested(s.Suggest(tt.query, tt.limit)); !slices.Equal(got, tt.want) {
+				t.Errorf("Suggest(%q, %d) = %q, want %q", tt.query, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggesterRenameAndRemove(t *testing.T) {
+	s := NewSuggester()
+	s.Put("1", "Caster sugar")
+	s.Put("2", "Icing sugar")
+
+	s.Put("1", "Superfine sugar")
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"cast", []string{}},
+		{"super", []string{"Superfine sugar"}},
+		{"sugar", []string{"Icing sugar", "Superfine sugar"}},
+	}
+	for _, tt := range tests {
+		if got := suggested(s.Suggest(tt.query, 10)); !slices.Equal(got, tt.want) {
+			t.Errorf("after renaming, Suggest(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	s.Remove("2")
+	if got := suggested(s.Suggest("sugar", 10)); !slices.Equal(got, []string{"Superfine sugar"}) {
+		t.Errorf("after removing, Suggest(sugar) = %q", got)
+	}
+	if got := suggested(s.Suggest("icing", 10)); len(got) != 0 {
+		t.Errorf("after removing, Suggest(icing) = %q", got)
+	}
+
+	s.Remove("1")
+	s.Remove("1")
+	if len(s.root.children) != 0 || len(s.names) != 0 {
+		t.Errorf("%d trie branches and %d names left after removing everything", len(s.root.children), len(s.names))
+	}
+}
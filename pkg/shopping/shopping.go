@@ -0,0 +1,193 @@
+package shopping
+
+import (
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/units"
+	"sort"
+	"strings"
+)
+
+// Uncategorized is the category of ingredients without one. Its group comes
+// last.
+const Uncategorized = "other"
+
+// Portion is a recipe to be cooked for a number of servings. Zero servings
+// means the recipe's own serving count.
+type Portion struct {
+	Recipe   model.Recipe
+	Servings int
+}
+
+// amount is a quantity in a known unit.
+type amount struct {
+	quantity float64
+	unit     units.Unit
+}
+
+// need accumulates the amounts of one ingredient that the recipes call for
+// and that are in stock.
+type need struct {
+	ingredient model.Ingredient
+	required   []amount
+	stock      []amount
+	// unmeasured is set by lines without a usable unit, e.g. salt "to
+	// taste", until some of the ingredient is found in stock.
+	unmeasured bool
+}
+
+func (n *need) add(quantity float64, unit string) {
+	u, ok := units.Lookup(unit)
+	if !ok {
+		n.unmeasured = true
+		return
+	}
+	n.required = append(n.required, amount{quantity, u})
+}
+
+func (n *need) subtract(quantity float64, unit string) {
+	n.unmeasured = false
+	if u, ok := units.Lookup(unit); ok {
+		n.stock = append(n.stock, amount{quantity, u})
+	}
+}
+
+// dimensions returns the dimensions to sum the required amounts in: the one
+// most lines use first, preferring mass, then volume, then count on a tie,
+// followed by the others in order of appearance.
+func (n *need) dimensions() []units.Dimension {
+	lines := make(map[units.Dimension]int)
+	var dims []units.Dimension
+	for _, a := range n.required {
+		if lines[a.unit.Dimension] == 0 {
+			dims = append(dims, a.unit.Dimension)
+		}
+		lines[a.unit.Dimension]++
+	}
+	rank := map[units.Dimension]int{units.Mass: 0, units.Volume: 1, units.Count: 2}
+	sort.SliceStable(dims, func(i, j int) bool {
+		if lines[dims[i]] != lines[dims[j]] {
+			return lines[dims[i]] > lines[dims[j]]
+		}
+		return rank[dims[i]] < rank[dims[j]]
+	})
+	return dims
+}
+
+// items returns what is left to buy, in the most readable unit of system.
+// Every amount goes to the first of dimensions it can be converted to.
+func (n *need) items(system units.System) []model.ShoppingItem {
+	dims := n.dimensions()
+	totals := make(map[units.Dimension]float64, len(dims))
+	sum := func(amounts []amount, sign float64) {
+		for _, a := range amounts {
+			for _, dim := range dims {
+				base, err := units.Convert(a.quantity, a.unit.Symbol, baseUnit(dim).Symbol, n.ingredient.Conversion())
+				if err == nil {
+					totals[dim] += sign * base
+					break
+				}
+			}
+		}
+	}
+	sum(n.required, 1)
+	sum(n.stock, -1)
+
+	item := model.ShoppingItem{
+		IngredientID: n.ingredient.ObjectID.Hex(),
+		Name:         n.ingredient.Name,
+		Category:     category(n.ingredient),
+	}
+	var items []model.ShoppingItem
+	for _, dim := range dims {
+		// Leave out float noise and what the stock covers.
+		base := totals[dim]
+		if base < 1e-9 {
+			continue
+		}
+		unit := units.Best(base, dim, system)
+		item.Quantity = units.Round(base/unit.Factor, unit)
+		item.Unit = unit.Symbol
+		items = append(items, item)
+	}
+	if len(items) == 0 && n.unmeasured {
+		items = append(items, item)
+	}
+	return items
+}
+
+// baseUnit returns the unit amounts of a dimension are accumulated in.
+func baseUnit(dim units.Dimension) units.Unit {
+	switch dim {
+	case units.Mass:
+		return units.Gram
+	case units.Volume:
+		return units.Millilitre
+	}
+	return units.Piece
+}
+
+func category(ingredient model.Ingredient) string {
+	if c := strings.ToLower(strings.TrimSpace(ingredient.Category)); c != "" {
+		return c
+	}
+	return Uncategorized
+}
+
+// Build consolidates the ingredients of the given portions into one shopping
+// list, grouped by ingredient category. Amounts of the same ingredient are
+// summed across recipes, converting between mass, volume and count where the
+// ingredient's density data allows it; amounts that cannot be converted are
+// listed separately. Whatever stock holds is subtracted, and ingredients the
+// stock fully covers are left out.
+func Build(portions []Portion, stock []model.PantryItem, system units.System) []model.ShoppingGroup {
+	needs := make(map[string]*need)
+	var order []string
+	for _, portion := range portions {
+		base := portion.Recipe.Servings
+		if base <= 0 {
+			base = 1
+		}
+		factor := 1.0
+		if portion.Servings > 0 {
+			factor = float64(portion.Servings) / float64(base)
+		}
+
+		for _, line := range portion.Recipe.Ingredients {
+			id := line.ObjectID.Hex()
+			n, ok := needs[id]
+			if !ok {
+				n = &need{ingredient: line.Ingredient}
+				needs[id] = n
+				order = append(order, id)
+			}
+			n.add(line.Quantity*factor, line.Unit)
+		}
+	}
+
+	for _, item := range stock {
+		if n, ok := needs[item.IngredientID]; ok {
+			n.subtract(item.Quantity, item.Unit)
+		}
+	}
+
+	byCategory := make(map[string][]model.ShoppingItem)
+	for _, id := range order {
+		for _, item := range needs[id].items(system) {
+			byCategory[item.Category] = append(byCategory[item.Category], item)
+		}
+	}
+
+	groups := make([]model.ShoppingGroup, 0, len(byCategory))
+	for c, items := range byCategory {
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+		groups = append(groups, model.ShoppingGroup{Category: c, Items: items})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i].Category, groups[j].Category
+		if (a == Uncategorized) != (b == Uncategorized) {
+			return b == Uncategorized
+		}
+		return a < b
+	})
+	return groups
+}
@@ -0,0 +1,128 @@
+package shopping
+
+import (
+	"dynamicrecipes/pkg/model"
+	"dynamicrecipes/pkg/units"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	flour   = model.Ingredient{ObjectID: primitive.NewObjectID(), Name: "flour", Category: "Baking", Density: 0.53}
+	milk    = model.Ingredient{ObjectID: primitive.NewObjectID(), Name: "milk", Category: "dairy", Density: 1.03}
+	egg     = model.Ingredient{ObjectID: primitive.NewObjectID(), Name: "egg", Category: "dairy", GramsPerPiece: 50}
+	salt    = model.Ingredient{ObjectID: primitive.NewObjectID(), Name: "salt"}
+	vanilla = model.Ingredient{ObjectID: primitive.NewObjectID(), Name: "vanilla"}
+)
+
+func recipe(servings int, lines ...model.RecipeIngredient) model.Recipe {
+	return model.Recipe{ObjectID: primitive.NewObjectID(), Servings: servings, Ingredients: lines}
+}
+
+func line(i model.Ingredient, q float64, unit string) model.RecipeIngredient {
+	return model.RecipeIngredient{Ingredient: i, Quantity: q, Unit: unit}
+}
+
+func stock(i model.Ingredient, q float64, unit string) model.PantryItem {
+	return model.PantryItem{IngredientID: i.ObjectID.Hex(), Quantity: q, Unit: unit}
+}
+
+// lines flattens a shopping list to "category: name quantity unit" lines.
+func lines(groups []model.ShoppingGroup) []string {
+	var out []string
+	for _, group := range groups {
+		for _, item := range group.Items {
+			out = append(out, strings.TrimSpace(fmt.Sprintf("%s: %s %.6g %s", group.Category, item.Name, item.Quantity, item.Unit)))
+		}
+	}
+	return out
+}
+
+func TestBuild(t *testing.T) {
+	// pancakes serve 2 and leave the salt to taste.
+	pancakes := recipe(2, line(flour, 200, "g"), line(milk, 1, "cup"), line(egg, 2, "pc"), line(salt, 0, ""))
+	bread := recipe(1, line(flour, 0.5, "kg"), line(salt, 10, "g"))
+
+	tests := []struct {
+		name     string
+		portions []Portion
+		stock    []model.PantryItem
+		system   units.System
+		want     []string
+	}{
+		{
+			name:     "one recipe",
+			portions: []Portion{{Recipe: pancakes}},
+			system:   units.Metric,
+			want:     []string{"baking: flour 200 g", "dairy: egg 2 pc", "dairy: milk 235 ml", "other: salt 0"},
+		},
+		{
+			name:     "servings scale the amounts",
+			portions: []Portion{{Recipe: pancakes, Servings: 4}},
+			system:   units.Metric,
+			want:     []string{"baking: flour 400 g", "dairy: egg 4 pc", "dairy: milk 475 ml", "other: salt 0"},
+		},
+		{
+			name:     "same ingredient across recipes in different units",
+			portions: []Portion{{Recipe: pancakes}, {Recipe: bread}},
+			system:   units.Metric,
+			want:     []string{"baking: flour 700 g", "dairy: egg 2 pc", "dairy: milk 235 ml", "other: salt 10 g"},
+		},
+		{
+			name:     "imperial",
+			portions: []Portion{{Recipe: pancakes}, {Recipe: bread}},
+			system:   units.Imperial,
+			want:     []string{"baking: flour 1.54 lb", "dairy: egg 2 pc", "dairy: milk 1 cup", "other: salt 0.25 oz"},
+		},
+		{
+			name: "mass and volume merged through density",
+			portions: []Portion{
+				{Recipe: recipe(1, line(flour, 1, "cup"), line(milk, 100, "g"))},
+				{Recipe: recipe(1, line(flour, 100, "g"), line(milk, 1, "cup"))},
+			},
+			system: units.Metric,
+			// 125.4 g + 100 g of flour, 100 g + 243.7 g of milk.
+			want: []string{"baking: flour 225 g", "dairy: milk 345 g"},
+		},
+		{
+			name: "amounts without density data are listed apart",
+			portions: []Portion{
+				{Recipe: recipe(1, line(vanilla, 1, "tsp"))},
+				{Recipe: recipe(1, line(vanilla, 5, "g"))},
+			},
+			system: units.Metric,
+			want:   []string{"other: vanilla 5 g", "other: vanilla 4.9 ml"},
+		},
+		{
+			name:     "pantry subtraction",
+			portions: []Portion{{Recipe: pancakes}},
+			stock: []model.PantryItem{
+				stock(flour, 1, "cup"), // 125.4 g
+				stock(milk, 2, "cups"),
+				stock(egg, 1, "each"),
+				stock(salt, 1, "g"),
+				stock(vanilla, 1, "tsp"),
+			},
+			system: units.Metric,
+			want:   []string{"baking: flour 75 g", "dairy: egg 1 pc"},
+		},
+		{
+			name:     "pantry covering everything",
+			portions: []Portion{{Recipe: bread}},
+			stock:    []model.PantryItem{stock(flour, 1, "kg"), stock(salt, 10, "g")},
+			system:   units.Metric,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lines(Build(tt.portions, tt.stock, tt.system)); !slices.Equal(got, tt.want) {
+				t.Errorf("Build() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
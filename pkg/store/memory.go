@@ -0,0 +1,662 @@
+package store
+
+import (
+	"context"
+	"dynamicrecipes/pkg/model"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryStore is a Store that keeps everything in process memory. It is meant
+// for local development and tests; nothing survives a restart.
+type MemoryStore struct {
+	ingredients *memoryIngredientStore
+	recipes     *memoryRecipeStore
+	pantry      *memoryPantryStore
+	lists       *memoryShoppingListStore
+	mealPlans   *memoryMealPlanStore
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		ingredients: &memoryIngredientStore{docs: make(map[primitive.ObjectID]model.Ingredient)},
+		recipes:     &memoryRecipeStore{docs: make(map[primitive.ObjectID]model.RecipeReturnType)},
+		pantry:      &memoryPantryStore{docs: make(map[primitive.ObjectID]model.PantryItem)},
+		lists:       &memoryShoppingListStore{docs: make(map[primitive.ObjectID]model.ShoppingList)},
+		mealPlans:   &memoryMealPlanStore{docs: make(map[primitive.ObjectID]model.MealPlanEntry)},
+	}
+}
+
+func (s *MemoryStore) Ingredients() IngredientStore { return s.ingredients }
+
+func (s *MemoryStore) Recipes() RecipeStore { return s.recipes }
+
+func (s *MemoryStore) Pantry() PantryStore { return s.pantry }
+
+func (s *MemoryStore) ShoppingLists() ShoppingListStore { return s.lists }
+
+func (s *MemoryStore) MealPlans() MealPlanStore { return s.mealPlans }
+
+// Close is a no-op for the in-memory backend.
+func (s *MemoryStore) Close(ctx context.Context) error { return nil }
+
+type memoryIngredientStore struct {
+	mu    sync.RWMutex
+	docs  map[primitive.ObjectID]model.Ingredient
+	order []primitive.ObjectID // insertion order, to list like a collection scan would
+}
+
+func (s *memoryIngredientStore) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Ingredient, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ingredient, ok := s.docs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &ingredient, nil
+}
+
+func (s *memoryIngredientStore) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.Ingredient, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]model.Ingredient, 0, len(ids))
+	for _, id := range ids {
+		if ingredient, ok := s.docs[id]; ok {
+			results = append(results, ingredient)
+		}
+	}
+	return results, nil
+}
+
+func (s *memoryIngredientStore) List(ctx context.Context) ([]model.Ingredient, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]model.Ingredient, 0, len(s.order))
+	for _, id := range s.order {
+		results = append(results, s.docs[id])
+	}
+	return results, nil
+}
+
+func (s *memoryIngredientStore) Query(ctx context.Context, q ListQuery) ([]model.Ingredient, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := make([]model.Ingredient, 0, len(s.order))
+	for _, id := range s.order {
+		all = append(all, s.docs[id])
+	}
+	return page(all, q, IngredientPosition), nil
+}
+
+func (s *memoryIngredientStore) InsertMany(ctx context.Context, ingredients []model.Ingredient) ([]primitive.ObjectID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]primitive.ObjectID, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		if ingredient.ObjectID.IsZero() {
+			ingredient.ObjectID = primitive.NewObjectID()
+		}
+		ingredient.Version = 1
+		s.docs[ingredient.ObjectID] = ingredient
+		s.order = append(s.order, ingredient.ObjectID)
+		ids = append(ids, ingredient.ObjectID)
+	}
+	return ids, nil
+}
+
+func (s *memoryIngredientStore) UpdateByID(ctx context.Context, id primitive.ObjectID, version int64, update model.IngredientUpdate) (*model.Ingredient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ingredient, ok := s.docs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if ingredient.Version != version {
+		return nil, ErrVersionConflict
+	}
+	update.Apply(&ingredient)
+	ingredient.Version++
+	s.docs[id] = ingredient
+	return &ingredient, nil
+}
+
+func (s *memoryIngredientStore) FindByName(ctx context.Context, name string) (*model.Ingredient, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, id := range s.order {
+		if ingredient := s.docs[id]; ingredient.Name == name {
+			return &ingredient, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memoryIngredientStore) DeleteByID(ctx context.Context, id primitive.ObjectID, version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ingredient, ok := s.docs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if ingredient.Version != version {
+		return ErrVersionConflict
+	}
+	delete(s.docs, id)
+	s.order = removeID(s.order, id)
+	return nil
+}
+
+type memoryRecipeStore struct {
+	mu    sync.RWMutex
+	docs  map[primitive.ObjectID]model.RecipeReturnType
+	order []primitive.ObjectID
+}
+
+func (s *memoryRecipeStore) FindByID(ctx context.Context, id primitive.ObjectID) (*model.RecipeReturnType, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	recipe, ok := s.docs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &recipe, nil
+}
+
+func (s *memoryRecipeStore) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.RecipeReturnType, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]model.RecipeReturnType, 0, len(ids))
+	for _, id := range ids {
+		if recipe, ok := s.docs[id]; ok {
+			results = append(results, recipe)
+		}
+	}
+	return results, nil
+}
+
+func (s *memoryRecipeStore) List(ctx context.Context) ([]model.RecipeReturnType, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]model.RecipeReturnType, 0, len(s.order))
+	for _, id := range s.order {
+		results = append(results, s.docs[id])
+	}
+	return results, nil
+}
+
+func (s *memoryRecipeStore) Query(ctx context.Context, q ListQuery) ([]model.RecipeReturnType, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := make([]model.RecipeReturnType, 0, len(s.order))
+	for _, id := range s.order {
+		all = append(all, s.docs[id])
+	}
+	return page(all, q, RecipePosition), nil
+}
+
+func (s *memoryRecipeStore) InsertMany(ctx context.Context, recipes []model.RecipePostType) ([]primitive.ObjectID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]primitive.ObjectID, 0, len(recipes))
+	for _, recipe := range recipes {
+		id := primitive.NewObjectID()
+		details := recipe.RecipeDetails
+		details.Instructions = append([]string(nil), recipe.Instructions...)
+		s.docs[id] = model.RecipeReturnType{
+			ObjectID:           id,
+			Name:               recipe.Name,
+			ID:                 append([]model.IngredientIDType(nil), recipe.Ingredients...),
+			Servings:           recipe.Servings,
+			RecipeDetails:      details,
+			CaloriesPerServing: recipe.CaloriesPerServing,
+			Version:            1,
+		}
+		s.order = append(s.order, id)
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (s *memoryRecipeStore) UpdateByID(ctx context.Context, id primitive.ObjectID, version int64, update model.RecipeUpdate) (*model.RecipeReturnType, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recipe, ok := s.docs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if recipe.Version != version {
+		return nil, ErrVersionConflict
+	}
+	update.Apply(&recipe)
+	recipe.Version++
+	s.docs[id] = recipe
+	return &recipe, nil
+}
+
+func (s *memoryRecipeStore) DeleteByID(ctx context.Context, id primitive.ObjectID, version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recipe, ok := s.docs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if recipe.Version != version {
+		return ErrVersionConflict
+	}
+	delete(s.docs, id)
+	s.order = removeID(s.order, id)
+	return nil
+}
+
+func (s *memoryRecipeStore) SetCalories(ctx context.Context, id primitive.ObjectID, calories int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recipe, ok := s.docs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	recipe.CaloriesPerServing = calories
+	s.docs[id] = recipe
+	return nil
+}
+
+// referencesIngredient reports whether a recipe has a line for the ingredient.
+func referencesIngredient(recipe model.RecipeReturnType, ingredientID primitive.ObjectID) bool {
+	for _, line := range recipe.ID {
+		if line.ObjectID == ingredientID.Hex() {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memoryRecipeStore) FindByIngredient(ctx context.Context, ingredientID primitive.ObjectID) ([]model.RecipeReturnType, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var results []model.RecipeReturnType
+	for _, id := range s.order {
+		if recipe := s.docs[id]; referencesIngredient(recipe, ingredientID) {
+			results = append(results, recipe)
+		}
+	}
+	return results, nil
+}
+
+func (s *memoryRecipeStore) DeleteByIngredient(ctx context.Context, ingredientID primitive.ObjectID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for _, id := range append([]primitive.ObjectID(nil), s.order...) {
+		if referencesIngredient(s.docs[id], ingredientID) {
+			delete(s.docs, id)
+			s.order = removeID(s.order, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *memoryRecipeStore) RemoveIngredient(ctx context.Context, ingredientID primitive.ObjectID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var modified int64
+	for id, recipe := range s.docs {
+		if !referencesIngredient(recipe, ingredientID) {
+			continue
+		}
+		lines := make([]model.IngredientIDType, 0, len(recipe.ID))
+		for _, line := range recipe.ID {
+			if line.ObjectID != ingredientID.Hex() {
+				lines = append(lines, line)
+			}
+		}
+		recipe.ID = lines
+		recipe.Version++
+		s.docs[id] = recipe
+		modified++
+	}
+	return modified, nil
+}
+
+type memoryPantryStore struct {
+	mu    sync.RWMutex
+	docs  map[primitive.ObjectID]model.PantryItem
+	order []primitive.ObjectID
+}
+
+func (s *memoryPantryStore) FindByID(ctx context.Context, id primitive.ObjectID) (*model.PantryItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, ok := s.docs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &item, nil
+}
+
+func (s *memoryPantryStore) ListByUser(ctx context.Context, userID string) ([]model.PantryItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]model.PantryItem, 0)
+	for _, id := range s.order {
+		if item := s.docs[id]; item.UserID == userID {
+			results = append(results, item)
+		}
+	}
+	return results, nil
+}
+
+func (s *memoryPantryStore) ExpiringBefore(ctx context.Context, userID string, before time.Time) ([]model.PantryItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]model.PantryItem, 0)
+	for _, id := range s.order {
+		if item := s.docs[id]; item.UserID == userID && item.ExpiresAt != nil && item.ExpiresAt.Before(before) {
+			results = append(results, item)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].ExpiresAt.Before(*results[j].ExpiresAt)
+	})
+	return results, nil
+}
+
+func (s *memoryPantryStore) InsertMany(ctx context.Context, items []model.PantryItem) ([]primitive.ObjectID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]primitive.ObjectID, 0, len(items))
+	for _, item := range items {
+		if item.ObjectID.IsZero() {
+			item.ObjectID = primitive.NewObjectID()
+		}
+		s.docs[item.ObjectID] = item
+		s.order = append(s.order, item.ObjectID)
+		ids = append(ids, item.ObjectID)
+	}
+	return ids, nil
+}
+
+func (s *memoryPantryStore) UpdateByID(ctx context.Context, id primitive.ObjectID, update model.PantryItemUpdate) (*model.PantryItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.docs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	update.Apply(&item)
+	s.docs[id] = item
+	return &item, nil
+}
+
+func (s *memoryPantryStore) DeleteByID(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.docs[id]; !ok {
+		return 0, nil
+	}
+	delete(s.docs, id)
+	s.order = removeID(s.order, id)
+	return 1, nil
+}
+
+type memoryShoppingListStore struct {
+	mu    sync.RWMutex
+	docs  map[primitive.ObjectID]model.ShoppingList
+	order []primitive.ObjectID
+}
+
+// copyShoppingList returns a copy of list that shares no slices with it.
+func copyShoppingList(list model.ShoppingList) *model.ShoppingList {
+	list.Members = append([]string{}, list.Members...)
+	list.Items = append([]model.ShoppingListItem{}, list.Items...)
+	return &list
+}
+
+func (s *memoryShoppingListStore) FindByID(ctx context.Context, id primitive.ObjectID) (*model.ShoppingList, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list, ok := s.docs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyShoppingList(list), nil
+}
+
+func (s *memoryShoppingListStore) ListByUser(ctx context.Context, userID string) ([]model.ShoppingList, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]model.ShoppingList, 0)
+	for _, id := range s.order {
+		list := s.docs[id]
+		if list.OwnerID == userID || slices.Contains(list.Members, userID) {
+			results = append(results, *copyShoppingList(list))
+		}
+	}
+	return results, nil
+}
+
+func (s *memoryShoppingListStore) Insert(ctx context.Context, list model.ShoppingList) (*model.ShoppingList, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list = *copyShoppingList(list)
+	list.ObjectID = primitive.NewObjectID()
+	for i := range list.Items {
+		list.Items[i].ItemID = primitive.NewObjectID()
+	}
+	list.Version = 1
+	list.UpdatedAt = time.Now().UTC()
+	s.docs[list.ObjectID] = list
+	s.order = append(s.order, list.ObjectID)
+	return copyShoppingList(list), nil
+}
+
+// modify applies change to a copy of the list with the given ID and stores it
+// as the next version, unless change fails.
+func (s *memoryShoppingListStore) modify(id primitive.ObjectID, change func(list *model.ShoppingList) error) (*model.ShoppingList, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.docs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	list := copyShoppingList(current)
+	if err := change(list); err != nil {
+		return nil, err
+	}
+	list.Version++
+	list.UpdatedAt = time.Now().UTC()
+	s.docs[id] = *list
+	return copyShoppingList(*list), nil
+}
+
+func (s *memoryShoppingListStore) UpdateByID(ctx context.Context, id primitive.ObjectID, update model.ShoppingListUpdate) (*model.ShoppingList, error) {
+	return s.modify(id, func(list *model.ShoppingList) error {
+		update.Apply(list)
+		return nil
+	})
+}
+
+func (s *memoryShoppingListStore) DeleteByID(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.docs[id]; !ok {
+		return 0, nil
+	}
+	delete(s.docs, id)
+	s.order = removeID(s.order, id)
+	return 1, nil
+}
+
+func (s *memoryShoppingListStore) AddItems(ctx context.Context, id primitive.ObjectID, items []model.ShoppingListItem) (*model.ShoppingList, error) {
+	return s.modify(id, func(list *model.ShoppingList) error {
+		for _, item := range items {
+			item.ItemID = primitive.NewObjectID()
+			list.Items = append(list.Items, item)
+		}
+		return nil
+	})
+}
+
+func (s *memoryShoppingListStore) UpdateItem(ctx context.Context, id, itemID primitive.ObjectID, update model.ShoppingListItemUpdate) (*model.ShoppingList, error) {
+	return s.modify(id, func(list *model.ShoppingList) error {
+		for i := range list.Items {
+			if list.Items[i].ItemID == itemID {
+				update.Apply(&list.Items[i])
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+}
+
+func (s *memoryShoppingListStore) RemoveItem(ctx context.Context, id, itemID primitive.ObjectID) (*model.ShoppingList, error) {
+	return s.modify(id, func(list *model.ShoppingList) error {
+		for i := range list.Items {
+			if list.Items[i].ItemID == itemID {
+				list.Items = append(list.Items[:i], list.Items[i+1:]...)
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+}
+
+type memoryMealPlanStore struct {
+	mu    sync.RWMutex
+	docs  map[primitive.ObjectID]model.MealPlanEntry
+	order []primitive.ObjectID
+}
+
+// inRange reports whether entry belongs to the user and is dated from from up
+// to but excluding to.
+func inRange(entry model.MealPlanEntry, userID string, from, to time.Time) bool {
+	return entry.UserID == userID && !entry.Date.Before(from) && entry.Date.Before(to)
+}
+
+func (s *memoryMealPlanStore) FindByID(ctx context.Context, id primitive.ObjectID) (*model.MealPlanEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.docs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &entry, nil
+}
+
+func (s *memoryMealPlanStore) ListByUser(ctx context.Context, userID string, from, to time.Time) ([]model.MealPlanEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]model.MealPlanEntry, 0)
+	for _, id := range s.order {
+		if entry := s.docs[id]; inRange(entry, userID, from, to) {
+			results = append(results, entry)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Date.Before(results[j].Date)
+	})
+	return results, nil
+}
+
+func (s *memoryMealPlanStore) InsertMany(ctx context.Context, entries []model.MealPlanEntry) ([]primitive.ObjectID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]primitive.ObjectID, 0, len(entries))
+	for _, entry := range entries {
+		if entry.ObjectID.IsZero() {
+			entry.ObjectID = primitive.NewObjectID()
+		}
+		s.docs[entry.ObjectID] = entry
+		s.order = append(s.order, entry.ObjectID)
+		ids = append(ids, entry.ObjectID)
+	}
+	return ids, nil
+}
+
+func (s *memoryMealPlanStore) UpdateByID(ctx context.Context, id primitive.ObjectID, update model.MealPlanEntryUpdate) (*model.MealPlanEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.docs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	update.Apply(&entry)
+	s.docs[id] = entry
+	return &entry, nil
+}
+
+func (s *memoryMealPlanStore) DeleteByID(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.docs[id]; !ok {
+		return 0, nil
+	}
+	delete(s.docs, id)
+	s.order = removeID(s.order, id)
+	return 1, nil
+}
+
+func (s *memoryMealPlanStore) DeleteByUser(ctx context.Context, userID string, from, to time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for _, id := range append([]primitive.ObjectID(nil), s.order...) {
+		if inRange(s.docs[id], userID, from, to) {
+			delete(s.docs, id)
+			s.order = removeID(s.order, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// removeID returns ids without the first occurrence of id.
+func removeID(ids []primitive.ObjectID, id primitive.ObjectID) []primitive.ObjectID {
+	for i, existing := range ids {
+		if existing == id {
+			return append(ids[:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,843 @@
+package store
+
+import (
+	"context"
+	"dynamicrecipes/pkg/model"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const databaseName = "Recipe_Service"
+
+// MongoStore is a Store backed by the Recipe_Service MongoDB database.
+type MongoStore struct {
+	client      *mongo.Client
+	ingredients *mongoIngredientStore
+	recipes     *mongoRecipeStore
+	pantry      *mongoPantryStore
+	lists       *mongoShoppingListStore
+	mealPlans   *mongoMealPlanStore
+}
+
+// NewMongoStore creates a MongoStore on top of an already connected client.
+func NewMongoStore(client *mongo.Client) *MongoStore {
+	db := client.Database(databaseName)
+	return &MongoStore{
+		client:      client,
+		ingredients: &mongoIngredientStore{collection: db.Collection("Ingredients")},
+		recipes:     &mongoRecipeStore{collection: db.Collection("recipes")},
+		pantry:      &mongoPantryStore{collection: db.Collection("pantry")},
+		lists:       &mongoShoppingListStore{collection: db.Collection("shopping_lists")},
+		mealPlans:   &mongoMealPlanStore{collection: db.Collection("meal_plan")},
+	}
+}
+
+func (s *MongoStore) Ingredients() IngredientStore { return s.ingredients }
+
+func (s *MongoStore) Recipes() RecipeStore { return s.recipes }
+
+func (s *MongoStore) Pantry() PantryStore { return s.pantry }
+
+func (s *MongoStore) ShoppingLists() ShoppingListStore { return s.lists }
+
+func (s *MongoStore) MealPlans() MealPlanStore { return s.mealPlans }
+
+// EnsureIndexes creates the indexes that paged listings and ingredient
+// reference lookups rely on. Existing indexes are left untouched.
+func (s *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.ingredients.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "calories_per_gram", Value: 1}, {Key: "_id", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create ingredient indexes: %w", err)
+	}
+	_, err = s.recipes.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "calories_per_serving", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "ingredients.objectid", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create recipe indexes: %w", err)
+	}
+	_, err = s.pantry.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "expires_at", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create pantry indexes: %w", err)
+	}
+	_, err = s.lists.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "owner_id", Value: 1}}},
+		{Keys: bson.D{{Key: "members", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create shopping list indexes: %w", err)
+	}
+	_, err = s.mealPlans.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "date", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create meal plan indexes: %w", err)
+	}
+	return nil
+}
+
+// Close disconnects the underlying MongoDB client.
+func (s *MongoStore) Close(ctx context.Context) error {
+	return s.client.Disconnect(ctx)
+}
+
+type mongoIngredientStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoIngredientStore) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Ingredient, error) {
+	var ingredient model.Ingredient
+	if err := s.collection.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&ingredient); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &ingredient, nil
+}
+
+func (s *mongoIngredientStore) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.Ingredient, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	cur, err := s.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var results model.IngredientsResult
+	if err = cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *mongoIngredientStore) List(ctx context.Context) ([]model.Ingredient, error) {
+	cur, err := s.collection.Find(ctx, bson.D{{}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var results model.IngredientsResult
+	if err = cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *mongoIngredientStore) Query(ctx context.Context, q ListQuery) ([]model.Ingredient, error) {
+	filter, opts := mongoListQuery(q, "calories_per_gram")
+	cur, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var results model.IngredientsResult
+	if err = cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *mongoIngredientStore) InsertMany(ctx context.Context, ingredients []model.Ingredient) ([]primitive.ObjectID, error) {
+	docs := make([]interface{}, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		ingredient.Version = 1
+		docs = append(docs, ingredient)
+	}
+
+	result, err := s.collection.InsertMany(ctx, docs)
+	if err != nil {
+		return nil, err
+	}
+	return insertedObjectIDs(result)
+}
+
+func (s *mongoIngredientStore) UpdateByID(ctx context.Context, id primitive.ObjectID, version int64, update model.IngredientUpdate) (*model.Ingredient, error) {
+	set := bson.M{}
+	if update.Name != nil {
+		set["name"] = *update.Name
+	}
+	if update.Calories != nil {
+		set["calories_per_gram"] = *update.Calories
+	}
+	if update.Density != nil {
+		set["density_g_per_ml"] = *update.Density
+	}
+	if update.GramsPerPiece != nil {
+		set["grams_per_piece"] = *update.GramsPerPiece
+	}
+	if update.Protein != nil {
+		set["protein_per_gram"] = *update.Protein
+	}
+	if update.Fat != nil {
+		set["fat_per_gram"] = *update.Fat
+	}
+	if update.Carbs != nil {
+		set["carbs_per_gram"] = *update.Carbs
+	}
+	if update.Fiber != nil {
+		set["fiber_per_gram"] = *update.Fiber
+	}
+	if update.Category != nil {
+		set["category"] = *update.Category
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updatedIngredient model.Ingredient
+	err := s.collection.FindOneAndUpdate(ctx, versionFilter(id, version), versionedUpdate(set), opts).Decode(&updatedIngredient)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, missOrConflict(ctx, s.collection, id)
+		}
+		return nil, err
+	}
+	return &updatedIngredient, nil
+}
+
+func (s *mongoIngredientStore) FindByName(ctx context.Context, name string) (*model.Ingredient, error) {
+	var ingredient model.Ingredient
+	if err := s.collection.FindOne(ctx, bson.M{"name": name}).Decode(&ingredient); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &ingredient, nil
+}
+
+func (s *mongoIngredientStore) DeleteByID(ctx context.Context, id primitive.ObjectID, version int64) error {
+	result, err := s.collection.DeleteOne(ctx, versionFilter(id, version))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return missOrConflict(ctx, s.collection, id)
+	}
+	return nil
+}
+
+type mongoRecipeStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoRecipeStore) FindByID(ctx context.Context, id primitive.ObjectID) (*model.RecipeReturnType, error) {
+	var recipe model.RecipeReturnType
+	if err := s.collection.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&recipe); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &recipe, nil
+}
+
+func (s *mongoRecipeStore) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.RecipeReturnType, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	cur, err := s.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var results model.RecipeResult
+	if err = cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *mongoRecipeStore) List(ctx context.Context) ([]model.RecipeReturnType, error) {
+	cur, err := s.collection.Find(ctx, bson.D{{}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var results model.RecipeResult
+	if err = cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *mongoRecipeStore) Query(ctx context.Context, q ListQuery) ([]model.RecipeReturnType, error) {
+	filter, opts := mongoListQuery(q, "calories_per_serving")
+	cur, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var results model.RecipeResult
+	if err = cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *mongoRecipeStore) InsertMany(ctx context.Context, recipes []model.RecipePostType) ([]primitive.ObjectID, error) {
+	docs := make([]interface{}, 0, len(recipes))
+	for _, recipe := range recipes {
+		recipe.Version = 1
+		docs = append(docs, recipe)
+	}
+
+	result, err := s.collection.InsertMany(ctx, docs)
+	if err != nil {
+		return nil, err
+	}
+	return insertedObjectIDs(result)
+}
+
+func (s *mongoRecipeStore) UpdateByID(ctx context.Context, id primitive.ObjectID, version int64, update model.RecipeUpdate) (*model.RecipeReturnType, error) {
+	set := bson.M{}
+	if update.Name != nil {
+		set["name"] = *update.Name
+	}
+	if update.Ingredients != nil {
+		set["ingredients"] = *update.Ingredients
+	}
+	if update.Servings != nil {
+		set["servings"] = *update.Servings
+	}
+	if update.Instructions != nil {
+		set["instructions"] = *update.Instructions
+	}
+	if update.PrepMinutes != nil {
+		set["prep_minutes"] = *update.PrepMinutes
+	}
+	if update.CookMinutes != nil {
+		set["cook_minutes"] = *update.CookMinutes
+	}
+	if update.Difficulty != nil {
+		set["difficulty"] = *update.Difficulty
+	}
+	if update.SourceURL != nil {
+		set["source_url"] = *update.SourceURL
+	}
+	if update.Notes != nil {
+		set["notes"] = *update.Notes
+	}
+	if update.CaloriesPerServing != nil {
+		set["calories_per_serving"] = *update.CaloriesPerServing
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updatedRecipe model.RecipeReturnType
+	err := s.collection.FindOneAndUpdate(ctx, versionFilter(id, version), versionedUpdate(set), opts).Decode(&updatedRecipe)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, missOrConflict(ctx, s.collection, id)
+		}
+		return nil, err
+	}
+	return &updatedRecipe, nil
+}
+
+func (s *mongoRecipeStore) DeleteByID(ctx context.Context, id primitive.ObjectID, version int64) error {
+	result, err := s.collection.DeleteOne(ctx, versionFilter(id, version))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return missOrConflict(ctx, s.collection, id)
+	}
+	return nil
+}
+
+func (s *mongoRecipeStore) SetCalories(ctx context.Context, id primitive.ObjectID, calories int) error {
+	result, err := s.collection.UpdateByID(ctx, id, bson.M{"$set": bson.M{"calories_per_serving": calories}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ingredientRefFilter matches recipes whose ingredient lines reference the
+// given ingredient. References are stored as hex strings.
+func ingredientRefFilter(ingredientID primitive.ObjectID) bson.M {
+	return bson.M{"ingredients.objectid": ingredientID.Hex()}
+}
+
+func (s *mongoRecipeStore) FindByIngredient(ctx context.Context, ingredientID primitive.ObjectID) ([]model.RecipeReturnType, error) {
+	cur, err := s.collection.Find(ctx, ingredientRefFilter(ingredientID))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var results model.RecipeResult
+	if err = cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *mongoRecipeStore) DeleteByIngredient(ctx context.Context, ingredientID primitive.ObjectID) (int64, error) {
+	result, err := s.collection.DeleteMany(ctx, ingredientRefFilter(ingredientID))
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (s *mongoRecipeStore) RemoveIngredient(ctx context.Context, ingredientID primitive.ObjectID) (int64, error) {
+	update := bson.M{
+		"$pull": bson.M{"ingredients": bson.M{"objectid": ingredientID.Hex()}},
+		"$inc":  bson.M{"version": 1},
+	}
+	result, err := s.collection.UpdateMany(ctx, ingredientRefFilter(ingredientID), update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+type mongoPantryStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoPantryStore) FindByID(ctx context.Context, id primitive.ObjectID) (*model.PantryItem, error) {
+	var item model.PantryItem
+	if err := s.collection.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&item); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (s *mongoPantryStore) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]model.PantryItem, error) {
+	cur, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	results := make([]model.PantryItem, 0)
+	if err = cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *mongoPantryStore) ListByUser(ctx context.Context, userID string) ([]model.PantryItem, error) {
+	return s.find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+}
+
+func (s *mongoPantryStore) ExpiringBefore(ctx context.Context, userID string, before time.Time) ([]model.PantryItem, error) {
+	filter := bson.M{"user_id": userID, "expires_at": bson.M{"$lt": before}}
+	return s.find(ctx, filter, options.Find().SetSort(bson.D{{Key: "expires_at", Value: 1}, {Key: "_id", Value: 1}}))
+}
+
+func (s *mongoPantryStore) InsertMany(ctx context.Context, items []model.PantryItem) ([]primitive.ObjectID, error) {
+	docs := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		docs = append(docs, item)
+	}
+
+	result, err := s.collection.InsertMany(ctx, docs)
+	if err != nil {
+		return nil, err
+	}
+	return insertedObjectIDs(result)
+}
+
+func (s *mongoPantryStore) UpdateByID(ctx context.Context, id primitive.ObjectID, update model.PantryItemUpdate) (*model.PantryItem, error) {
+	set := bson.M{}
+	if update.Quantity != nil {
+		set["quantity"] = *update.Quantity
+	}
+	if update.Unit != nil {
+		set["unit"] = *update.Unit
+	}
+	if update.PurchasedAt != nil {
+		set["purchased_at"] = *update.PurchasedAt
+	}
+	unset := bson.M{}
+	if update.ClearExpiresAt {
+		unset["expires_at"] = ""
+	} else if update.ExpiresAt != nil {
+		set["expires_at"] = *update.ExpiresAt
+	}
+	if len(set) == 0 && len(unset) == 0 {
+		return s.FindByID(ctx, id)
+	}
+
+	change := bson.M{}
+	if len(set) > 0 {
+		change["$set"] = set
+	}
+	if len(unset) > 0 {
+		change["$unset"] = unset
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updatedItem model.PantryItem
+	err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, change, opts).Decode(&updatedItem)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &updatedItem, nil
+}
+
+func (s *mongoPantryStore) DeleteByID(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	result, err := s.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: id}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+type mongoShoppingListStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoShoppingListStore) FindByID(ctx context.Context, id primitive.ObjectID) (*model.ShoppingList, error) {
+	var list model.ShoppingList
+	if err := s.collection.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&list); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (s *mongoShoppingListStore) ListByUser(ctx context.Context, userID string) ([]model.ShoppingList, error) {
+	filter := bson.M{"$or": bson.A{bson.M{"owner_id": userID}, bson.M{"members": userID}}}
+	cur, err := s.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	results := make([]model.ShoppingList, 0)
+	if err = cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *mongoShoppingListStore) Insert(ctx context.Context, list model.ShoppingList) (*model.ShoppingList, error) {
+	list.ObjectID = primitive.NewObjectID()
+	list.Members = append([]string{}, list.Members...)
+	list.Items = append([]model.ShoppingListItem{}, list.Items...)
+	for i := range list.Items {
+		list.Items[i].ItemID = primitive.NewObjectID()
+	}
+	list.Version = 1
+	list.UpdatedAt = time.Now().UTC()
+
+	if _, err := s.collection.InsertOne(ctx, list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// modify applies update to the list matched by filter as its next version and
+// returns the result, or ErrNotFound if nothing matches.
+func (s *mongoShoppingListStore) modify(ctx context.Context, filter bson.M, update bson.M) (*model.ShoppingList, error) {
+	set, _ := update["$set"].(bson.M)
+	if set == nil {
+		set = bson.M{}
+	}
+	set["updated_at"] = time.Now().UTC()
+	update["$set"] = set
+	update["$inc"] = bson.M{"version": 1}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var list model.ShoppingList
+	if err := s.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&list); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (s *mongoShoppingListStore) UpdateByID(ctx context.Context, id primitive.ObjectID, update model.ShoppingListUpdate) (*model.ShoppingList, error) {
+	set := bson.M{}
+	if update.Name != nil {
+		set["name"] = *update.Name
+	}
+	if update.Members != nil {
+		set["members"] = append([]string{}, *update.Members...)
+	}
+	return s.modify(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+}
+
+func (s *mongoShoppingListStore) DeleteByID(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	result, err := s.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: id}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (s *mongoShoppingListStore) AddItems(ctx context.Context, id primitive.ObjectID, items []model.ShoppingListItem) (*model.ShoppingList, error) {
+	docs := make(bson.A, 0, len(items))
+	for _, item := range items {
+		item.ItemID = primitive.NewObjectID()
+		docs = append(docs, item)
+	}
+	return s.modify(ctx, bson.M{"_id": id}, bson.M{"$push": bson.M{"items": bson.M{"$each": docs}}})
+}
+
+func (s *mongoShoppingListStore) UpdateItem(ctx context.Context, id, itemID primitive.ObjectID, update model.ShoppingListItemUpdate) (*model.ShoppingList, error) {
+	// The positional operator targets the item matched by the filter, so a
+	// check-off only touches that item even while others are being edited.
+	set := bson.M{}
+	if update.Name != nil {
+		set["items.$.name"] = *update.Name
+	}
+	if update.Quantity != nil {
+		set["items.$.quantity"] = *update.Quantity
+	}
+	if update.Unit != nil {
+		set["items.$.unit"] = *update.Unit
+	}
+	if update.Checked != nil {
+		set["items.$.checked"] = *update.Checked
+	}
+	return s.modify(ctx, bson.M{"_id": id, "items.item_id": itemID}, bson.M{"$set": set})
+}
+
+func (s *mongoShoppingListStore) RemoveItem(ctx context.Context, id, itemID primitive.ObjectID) (*model.ShoppingList, error) {
+	filter := bson.M{"_id": id, "items.item_id": itemID}
+	return s.modify(ctx, filter, bson.M{"$pull": bson.M{"items": bson.M{"item_id": itemID}}})
+}
+
+type mongoMealPlanStore struct {
+	collection *mongo.Collection
+}
+
+// mealPlanRange selects the entries of a user dated from from up to but
+// excluding to.
+func mealPlanRange(userID string, from, to time.Time) bson.M {
+	return bson.M{"user_id": userID, "date": bson.M{"$gte": from, "$lt": to}}
+}
+
+func (s *mongoMealPlanStore) FindByID(ctx context.Context, id primitive.ObjectID) (*model.MealPlanEntry, error) {
+	var entry model.MealPlanEntry
+	if err := s.collection.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&entry); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (s *mongoMealPlanStore) ListByUser(ctx context.Context, userID string, from, to time.Time) ([]model.MealPlanEntry, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := s.collection.Find(ctx, mealPlanRange(userID, from, to), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	results := make([]model.MealPlanEntry, 0)
+	if err = cur.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *mongoMealPlanStore) InsertMany(ctx context.Context, entries []model.MealPlanEntry) ([]primitive.ObjectID, error) {
+	docs := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		docs = append(docs, entry)
+	}
+
+	result, err := s.collection.InsertMany(ctx, docs)
+	if err != nil {
+		return nil, err
+	}
+	return insertedObjectIDs(result)
+}
+
+func (s *mongoMealPlanStore) UpdateByID(ctx context.Context, id primitive.ObjectID, update model.MealPlanEntryUpdate) (*model.MealPlanEntry, error) {
+	set := bson.M{}
+	if update.Date != nil {
+		set["date"] = *update.Date
+	}
+	if update.Slot != nil {
+		set["slot"] = *update.Slot
+	}
+	if update.RecipeID != nil {
+		set["recipe_id"] = *update.RecipeID
+	}
+	if update.Servings != nil {
+		set["servings"] = *update.Servings
+	}
+	if len(set) == 0 {
+		return s.FindByID(ctx, id)
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updatedEntry model.MealPlanEntry
+	err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": set}, opts).Decode(&updatedEntry)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &updatedEntry, nil
+}
+
+func (s *mongoMealPlanStore) DeleteByID(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	result, err := s.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: id}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (s *mongoMealPlanStore) DeleteByUser(ctx context.Context, userID string, from, to time.Time) (int64, error) {
+	result, err := s.collection.DeleteMany(ctx, mealPlanRange(userID, from, to))
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// mongoListQuery translates q into a filter and find options that the
+// (field, _id) indexes created by EnsureIndexes can serve. caloriesField names
+// the field SortCalories and the calorie bounds apply to.
+func mongoListQuery(q ListQuery, caloriesField string) (bson.M, *options.FindOptions) {
+	var conditions bson.A
+	if q.NamePrefix != "" {
+		conditions = append(conditions, bson.M{"name": bson.M{"$regex": "^" + regexp.QuoteMeta(q.NamePrefix)}})
+	}
+	if q.MinCalories != nil || q.MaxCalories != nil {
+		calories := bson.M{}
+		if q.MinCalories != nil {
+			calories["$gte"] = *q.MinCalories
+		}
+		if q.MaxCalories != nil {
+			calories["$lte"] = *q.MaxCalories
+		}
+		conditions = append(conditions, bson.M{caloriesField: calories})
+	}
+
+	var field string
+	var value any
+	switch q.Sort {
+	case SortName:
+		field = "name"
+		if q.After != nil {
+			value = q.After.Name
+		}
+	case SortCalories:
+		field = caloriesField
+		if q.After != nil {
+			value = q.After.Calories
+		}
+	}
+
+	direction, beyond := 1, "$gt"
+	if q.Desc {
+		direction, beyond = -1, "$lt"
+	}
+	if q.After != nil {
+		if field == "" {
+			conditions = append(conditions, bson.M{"_id": bson.M{beyond: q.After.ID}})
+		} else {
+			conditions = append(conditions, bson.M{"$or": bson.A{
+				bson.M{field: bson.M{beyond: value}},
+				bson.M{field: value, "_id": bson.M{beyond: q.After.ID}},
+			}})
+		}
+	}
+
+	filter := bson.M{}
+	if len(conditions) > 0 {
+		filter["$and"] = conditions
+	}
+	sort := bson.D{}
+	if field != "" {
+		sort = append(sort, bson.E{Key: field, Value: direction})
+	}
+	sort = append(sort, bson.E{Key: "_id", Value: direction})
+	opts := options.Find().SetSort(sort)
+	if q.Limit > 0 {
+		opts.SetLimit(int64(q.Limit))
+	}
+	return filter, opts
+}
+
+// versionFilter matches the document with the given ID only while it is at
+// version. Version 0 also matches documents written before versioning, which
+// have no version field at all.
+func versionFilter(id primitive.ObjectID, version int64) bson.M {
+	if version == 0 {
+		return bson.M{"_id": id, "version": bson.M{"$in": bson.A{0, nil}}}
+	}
+	return bson.M{"_id": id, "version": version}
+}
+
+// versionedUpdate sets the given fields and bumps the version in one update.
+func versionedUpdate(set bson.M) bson.M {
+	update := bson.M{"$inc": bson.M{"version": 1}}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	return update
+}
+
+// missOrConflict tells apart the two reasons a versioned update can match
+// nothing: the document is gone, or it moved to another version.
+func missOrConflict(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID) error {
+	count, err := collection.CountDocuments(ctx, bson.M{"_id": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return ErrVersionConflict
+}
+
+// insertedObjectIDs narrows the driver's InsertedIDs to ObjectIDs, which is
+// what MongoDB generates for documents without an explicit _id.
+func insertedObjectIDs(result *mongo.InsertManyResult) ([]primitive.ObjectID, error) {
+	ids := make([]primitive.ObjectID, 0, len(result.InsertedIDs))
+	for _, id := range result.InsertedIDs {
+		oid, ok := id.(primitive.ObjectID)
+		if !ok {
+			return nil, fmt.Errorf("unexpected inserted id type %T", id)
+		}
+		ids = append(ids, oid)
+	}
+	return ids, nil
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"bytes"
+	"dynamicrecipes/pkg/model"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SortField is a key listings can be ordered by. Ties are always broken by
+// ID, so every order is total and can be paged through with a Position.
+type SortField string
+
+const (
+	// SortCreated orders by ID, i.e. by creation time.
+	SortCreated SortField = "created"
+	SortName    SortField = "name"
+	// SortCalories orders ingredients by calories per gram and recipes by
+	// their stored calories per serving.
+	SortCalories SortField = "calories"
+)
+
+// Position is the sort key of the last item of a page. Only the field matching
+// the query's Sort is used, besides ID.
+type Position struct {
+	Name     string
+	Calories int
+	ID       primitive.ObjectID
+}
+
+// ListQuery selects one page of a collection.
+type ListQuery struct {
+	Sort SortField
+	Desc bool
+	// After resumes the listing behind the given item; nil starts at the top.
+	After *Position
+	// Limit caps the number of items returned; 0 means no limit.
+	Limit int
+
+	// NamePrefix keeps items whose name starts with it (case-sensitive, so
+	// it can use the name index).
+	NamePrefix string
+	// MinCalories and MaxCalories bound calories, inclusive, in the unit of
+	// SortCalories.
+	MinCalories, MaxCalories *int
+}
+
+// IngredientPosition returns the sort keys of an ingredient.
+func IngredientPosition(ingredient model.Ingredient) Position {
+	return Position{Name: ingredient.Name, Calories: ingredient.Calories, ID: ingredient.ObjectID}
+}
+
+// RecipePosition returns the sort keys of a recipe.
+func RecipePosition(recipe model.RecipeReturnType) Position {
+	return Position{Name: recipe.Name, Calories: recipe.CaloriesPerServing, ID: recipe.ObjectID}
+}
+
+// compare orders a and b the way q sorts, ascending; page uses it where the
+// database backends use their indexes.
+func (q ListQuery) compare(a, b Position) int {
+	var c int
+	switch q.Sort {
+	case SortName:
+		c = strings.Compare(a.Name, b.Name)
+	case SortCalories:
+		c = a.Calories - b.Calories
+	}
+	if c == 0 {
+		c = bytes.Compare(a.ID[:], b.ID[:])
+	}
+	return c
+}
+
+// after reports whether p comes after q.After in the requested direction.
+func (q ListQuery) after(p Position) bool {
+	if q.After == nil {
+		return true
+	}
+	c := q.compare(p, *q.After)
+	if q.Desc {
+		return c < 0
+	}
+	return c > 0
+}
+
+// matches applies the filters of q to an item.
+func (q ListQuery) matches(p Position) bool {
+	if !strings.HasPrefix(p.Name, q.NamePrefix) {
+		return false
+	}
+	if q.MinCalories != nil && p.Calories < *q.MinCalories {
+		return false
+	}
+	if q.MaxCalories != nil && p.Calories > *q.MaxCalories {
+		return false
+	}
+	return true
+}
+
+// page filters, orders and truncates items in memory according to q.
+func page[T any](items []T, q ListQuery, position func(T) Position) []T {
+	var results []T
+	for _, item := range items {
+		if p := position(item); q.matches(p) && q.after(p) {
+			results = append(results, item)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		c := q.compare(position(results[i]), position(results[j]))
+		if q.Desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results
+}
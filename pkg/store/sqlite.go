@@ -0,0 +1,1336 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"dynamicrecipes/pkg/model"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sqliteMigrations are applied in order on startup. The index of the last
+// applied migration plus one is kept in PRAGMA user_version, so new schema
+// changes must be appended rather than edited in place.
+var sqliteMigrations = []string{
+	`CREATE TABLE IF NOT EXISTS ingredients (
+		id                TEXT PRIMARY KEY,
+		name              TEXT NOT NULL,
+		calories_per_gram INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS ingredients_name ON ingredients (name);
+	CREATE TABLE IF NOT EXISTS recipes (
+		id   TEXT PRIMARY KEY,
+		name TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS recipe_ingredients (
+		recipe_id     TEXT NOT NULL REFERENCES recipes (id) ON DELETE CASCADE,
+		position      INTEGER NOT NULL,
+		ingredient_id TEXT NOT NULL,
+		PRIMARY KEY (recipe_id, position)
+	);
+	CREATE INDEX IF NOT EXISTS recipe_ingredients_ingredient ON recipe_ingredients (ingredient_id);`,
+	`ALTER TABLE recipe_ingredients ADD COLUMN quantity REAL NOT NULL DEFAULT 0;
+	ALTER TABLE recipe_ingredients ADD COLUMN unit TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE ingredients ADD COLUMN density_g_per_ml REAL NOT NULL DEFAULT 0;
+	ALTER TABLE ingredients ADD COLUMN grams_per_piece REAL NOT NULL DEFAULT 0;`,
+	`ALTER TABLE ingredients ADD COLUMN protein_per_gram REAL NOT NULL DEFAULT 0;
+	ALTER TABLE ingredients ADD COLUMN fat_per_gram REAL NOT NULL DEFAULT 0;
+	ALTER TABLE ingredients ADD COLUMN carbs_per_gram REAL NOT NULL DEFAULT 0;
+	ALTER TABLE ingredients ADD COLUMN fiber_per_gram REAL NOT NULL DEFAULT 0;
+	ALTER TABLE recipes ADD COLUMN servings INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE ingredients ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE recipes ADD COLUMN version INTEGER NOT NULL DEFAULT 0;`,
+	`DROP INDEX IF EXISTS ingredients_name;
+	CREATE INDEX IF NOT EXISTS ingredients_name_id ON ingredients (name, id);
+	CREATE INDEX IF NOT EXISTS ingredients_calories_id ON ingredients (calories_per_gram, id);
+	CREATE INDEX IF NOT EXISTS recipes_name_id ON recipes (name, id);`,
+	`CREATE TABLE IF NOT EXISTS pantry_items (
+		id            TEXT PRIMARY KEY,
+		user_id       TEXT NOT NULL,
+		ingredient_id TEXT NOT NULL,
+		quantity      REAL NOT NULL DEFAULT 0,
+		unit          TEXT NOT NULL DEFAULT '',
+		purchased_at  INTEGER NOT NULL,
+		expires_at    INTEGER
+	);
+	CREATE INDEX IF NOT EXISTS pantry_items_user_expires ON pantry_items (user_id, expires_at);
+	CREATE INDEX IF NOT EXISTS pantry_items_ingredient ON pantry_items (ingredient_id);`,
+	`ALTER TABLE ingredients ADD COLUMN category TEXT NOT NULL DEFAULT '';`,
+	`CREATE TABLE IF NOT EXISTS shopping_lists (
+		id         TEXT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		owner_id   TEXT NOT NULL,
+		version    INTEGER NOT NULL DEFAULT 1,
+		updated_at INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS shopping_lists_owner ON shopping_lists (owner_id);
+	CREATE TABLE IF NOT EXISTS shopping_list_members (
+		list_id TEXT NOT NULL REFERENCES shopping_lists (id) ON DELETE CASCADE,
+		user_id TEXT NOT NULL,
+		PRIMARY KEY (list_id, user_id)
+	);
+	CREATE INDEX IF NOT EXISTS shopping_list_members_user ON shopping_list_members (user_id);
+	CREATE TABLE IF NOT EXISTS shopping_list_items (
+		item_id       TEXT PRIMARY KEY,
+		list_id       TEXT NOT NULL REFERENCES shopping_lists (id) ON DELETE CASCADE,
+		position      INTEGER NOT NULL,
+		ingredient_id TEXT NOT NULL DEFAULT '',
+		name          TEXT NOT NULL,
+		category      TEXT NOT NULL DEFAULT '',
+		quantity      REAL NOT NULL DEFAULT 0,
+		unit          TEXT NOT NULL DEFAULT '',
+		checked       INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS shopping_list_items_list ON shopping_list_items (list_id, position);`,
+	`CREATE TABLE IF NOT EXISTS meal_plan_entries (
+		id        TEXT PRIMARY KEY,
+		user_id   TEXT NOT NULL,
+		date      INTEGER NOT NULL,
+		slot      TEXT NOT NULL,
+		recipe_id TEXT NOT NULL,
+		servings  INTEGER NOT NULL DEFAULT 1
+	);
+	CREATE INDEX IF NOT EXISTS meal_plan_entries_user_date ON meal_plan_entries (user_id, date);`,
+	`ALTER TABLE recipes ADD COLUMN prep_minutes INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE recipes ADD COLUMN cook_minutes INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE recipes ADD COLUMN difficulty TEXT NOT NULL DEFAULT '';
+	ALTER TABLE recipes ADD COLUMN source_url TEXT NOT NULL DEFAULT '';
+	ALTER TABLE recipes ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+	CREATE TABLE IF NOT EXISTS recipe_instructions (
+		recipe_id TEXT NOT NULL REFERENCES recipes (id) ON DELETE CASCADE,
+		position  INTEGER NOT NULL,
+		text      TEXT NOT NULL,
+		PRIMARY KEY (recipe_id, position)
+	);`,
+	`ALTER TABLE recipes ADD COLUMN calories_per_serving INTEGER NOT NULL DEFAULT 0;
+	CREATE INDEX IF NOT EXISTS recipes_calories_id ON recipes (calories_per_serving, id);`,
+}
+
+// SQLiteStore is a Store backed by an embedded SQLite database file, for
+// running the service without a MongoDB instance.
+type SQLiteStore struct {
+	db          *sql.DB
+	ingredients *sqliteIngredientStore
+	recipes     *sqliteRecipeStore
+	pantry      *sqlitePantryStore
+	lists       *sqliteShoppingListStore
+	mealPlans   *sqliteMealPlanStore
+}
+
+// NewSQLiteStore opens (creating if needed) the database at path and brings
+// its schema up to date.
+func NewSQLiteStore(ctx context.Context, path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	// SQLite serialises writers anyway; a single connection avoids
+	// "database is locked" errors under concurrent requests.
+	db.SetMaxOpenConns(1)
+
+	if err := migrateSQLite(ctx, db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStore{
+		db:          db,
+		ingredients: &sqliteIngredientStore{db: db},
+		recipes:     &sqliteRecipeStore{db: db},
+		pantry:      &sqlitePantryStore{db: db},
+		lists:       &sqliteShoppingListStore{db: db},
+		mealPlans:   &sqliteMealPlanStore{db: db},
+	}, nil
+}
+
+func migrateSQLite(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, sqliteMigrations[i]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Ingredients() IngredientStore { return s.ingredients }
+
+func (s *SQLiteStore) Recipes() RecipeStore { return s.recipes }
+
+func (s *SQLiteStore) Pantry() PantryStore { return s.pantry }
+
+func (s *SQLiteStore) ShoppingLists() ShoppingListStore { return s.lists }
+
+func (s *SQLiteStore) MealPlans() MealPlanStore { return s.mealPlans }
+
+// Close closes the database handle.
+func (s *SQLiteStore) Close(ctx context.Context) error {
+	return s.db.Close()
+}
+
+type sqliteIngredientStore struct {
+	db *sql.DB
+}
+
+const ingredientColumns = "id, name, calories_per_gram, density_g_per_ml, grams_per_piece, " +
+	"protein_per_gram, fat_per_gram, carbs_per_gram, fiber_per_gram, version, category"
+
+// ingredientValues returns the column values of an ingredient in ingredientColumns order.
+func ingredientValues(ingredient model.Ingredient) []any {
+	return []any{
+		ingredient.ObjectID.Hex(), ingredient.Name, ingredient.Calories, ingredient.Density, ingredient.GramsPerPiece,
+		ingredient.Protein, ingredient.Fat, ingredient.Carbs, ingredient.Fiber, ingredient.Version, ingredient.Category,
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanIngredient(row rowScanner) (model.Ingredient, error) {
+	var (
+		ingredient model.Ingredient
+		id         string
+	)
+	err := row.Scan(&id, &ingredient.Name, &ingredient.Calories, &ingredient.Density, &ingredient.GramsPerPiece,
+		&ingredient.Protein, &ingredient.Fat, &ingredient.Carbs, &ingredient.Fiber, &ingredient.Version, &ingredient.Category)
+	if err != nil {
+		return model.Ingredient{}, err
+	}
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return model.Ingredient{}, fmt.Errorf("corrupt ingredient id %q: %w", id, err)
+	}
+	ingredient.ObjectID = objID
+	return ingredient, nil
+}
+
+func (s *sqliteIngredientStore) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Ingredient, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+ingredientColumns+" FROM ingredients WHERE id = ?", id.Hex())
+	ingredient, err := scanIngredient(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &ingredient, nil
+}
+
+// sqliteMaxInParams bounds the number of placeholders per IN (...) clause so
+// large batches stay below SQLITE_MAX_VARIABLE_NUMBER on older builds.
+const sqliteMaxInParams = 500
+
+func (s *sqliteIngredientStore) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.Ingredient, error) {
+	var results model.IngredientsResult
+	for start := 0; start < len(ids); start += sqliteMaxInParams {
+		end := min(start+sqliteMaxInParams, len(ids))
+		args := make([]any, 0, end-start)
+		for _, id := range ids[start:end] {
+			args = append(args, id.Hex())
+		}
+
+		rows, err := s.db.QueryContext(ctx,
+			"SELECT "+ingredientColumns+" FROM ingredients WHERE id IN ("+placeholders(len(args))+")",
+			args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			ingredient, err := scanIngredient(rows)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			results = append(results, ingredient)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// placeholders returns n comma-separated "?" parameters.
+func placeholders(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strings.Repeat("?, ", n-1) + "?"
+}
+
+func (s *sqliteIngredientStore) List(ctx context.Context) ([]model.Ingredient, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+ingredientColumns+" FROM ingredients ORDER BY rowid")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results model.IngredientsResult
+	for rows.Next() {
+		ingredient, err := scanIngredient(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, ingredient)
+	}
+	return results, rows.Err()
+}
+
+// sqliteListClause translates q into a WHERE ... ORDER BY ... LIMIT suffix
+// that the (column, id) indexes can serve, with SortCalories and the calorie
+// bounds applying to caloriesColumn. Object IDs are stored as hex, which sorts
+// like the IDs themselves.
+func sqliteListClause(q ListQuery, caloriesColumn string) (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	if q.NamePrefix != "" {
+		// A range rather than LIKE, which would not use the index.
+		conditions = append(conditions, "name >= ? AND name < ?")
+		args = append(args, q.NamePrefix, q.NamePrefix+"\U0010FFFF")
+	}
+	if q.MinCalories != nil {
+		conditions = append(conditions, caloriesColumn+" >= ?")
+		args = append(args, *q.MinCalories)
+	}
+	if q.MaxCalories != nil {
+		conditions = append(conditions, caloriesColumn+" <= ?")
+		args = append(args, *q.MaxCalories)
+	}
+
+	var column string
+	switch q.Sort {
+	case SortName:
+		column = "name"
+	case SortCalories:
+		column = caloriesColumn
+	}
+
+	direction, beyond := "ASC", ">"
+	if q.Desc {
+		direction, beyond = "DESC", "<"
+	}
+	if q.After != nil {
+		switch q.Sort {
+		case SortName:
+			conditions = append(conditions, "(name, id) "+beyond+" (?, ?)")
+			args = append(args, q.After.Name, q.After.ID.Hex())
+		case SortCalories:
+			conditions = append(conditions, "("+caloriesColumn+", id) "+beyond+" (?, ?)")
+			args = append(args, q.After.Calories, q.After.ID.Hex())
+		default:
+			conditions = append(conditions, "id "+beyond+" ?")
+			args = append(args, q.After.ID.Hex())
+		}
+	}
+
+	var clause string
+	if len(conditions) > 0 {
+		clause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	if column != "" {
+		clause += " ORDER BY " + column + " " + direction + ", id " + direction
+	} else {
+		clause += " ORDER BY id " + direction
+	}
+	if q.Limit > 0 {
+		clause += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+	return clause, args
+}
+
+func (s *sqliteIngredientStore) Query(ctx context.Context, q ListQuery) ([]model.Ingredient, error) {
+	clause, args := sqliteListClause(q, "calories_per_gram")
+	rows, err := s.db.QueryContext(ctx, "SELECT "+ingredientColumns+" FROM ingredients "+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results model.IngredientsResult
+	for rows.Next() {
+		ingredient, err := scanIngredient(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, ingredient)
+	}
+	return results, rows.Err()
+}
+
+func (s *sqliteIngredientStore) InsertMany(ctx context.Context, ingredients []model.Ingredient) ([]primitive.ObjectID, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := make([]primitive.ObjectID, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		if ingredient.ObjectID.IsZero() {
+			ingredient.ObjectID = primitive.NewObjectID()
+		}
+		ingredient.Version = 1
+		values := ingredientValues(ingredient)
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO ingredients ("+ingredientColumns+") VALUES ("+placeholders(len(values))+")",
+			values...)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, ingredient.ObjectID)
+	}
+	return ids, tx.Commit()
+}
+
+func (s *sqliteIngredientStore) UpdateByID(ctx context.Context, id primitive.ObjectID, version int64, update model.IngredientUpdate) (*model.Ingredient, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ingredient, err := scanIngredient(tx.QueryRowContext(ctx, "SELECT "+ingredientColumns+" FROM ingredients WHERE id = ?", id.Hex()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if ingredient.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	update.Apply(&ingredient)
+	ingredient.Version++
+	_, err = tx.ExecContext(ctx,
+		"UPDATE ingredients SET name = ?, calories_per_gram = ?, density_g_per_ml = ?, grams_per_piece = ?, "+
+			"protein_per_gram = ?, fat_per_gram = ?, carbs_per_gram = ?, fiber_per_gram = ?, version = ?, category = ? WHERE id = ?",
+		append(ingredientValues(ingredient)[1:], id.Hex())...)
+	if err != nil {
+		return nil, err
+	}
+	return &ingredient, tx.Commit()
+}
+
+func (s *sqliteIngredientStore) FindByName(ctx context.Context, name string) (*model.Ingredient, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+ingredientColumns+" FROM ingredients WHERE name = ? ORDER BY rowid LIMIT 1", name)
+	ingredient, err := scanIngredient(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &ingredient, nil
+}
+
+func (s *sqliteIngredientStore) DeleteByID(ctx context.Context, id primitive.ObjectID, version int64) error {
+	return deleteVersioned(ctx, s.db, "ingredients", id, version)
+}
+
+// deleteVersioned deletes the row of a versioned table if it is still at
+// version, returning ErrNotFound or ErrVersionConflict like the updates do.
+func deleteVersioned(ctx context.Context, db *sql.DB, table string, id primitive.ObjectID, version int64) error {
+	result, err := db.ExecContext(ctx, "DELETE FROM "+table+" WHERE id = ? AND version = ?", id.Hex(), version)
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		return nil
+	}
+
+	var exists int
+	if err := db.QueryRowContext(ctx, "SELECT 1 FROM "+table+" WHERE id = ?", id.Hex()).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return ErrVersionConflict
+}
+
+type sqliteRecipeStore struct {
+	db *sql.DB
+}
+
+// sqliteQueryer is satisfied by both *sql.DB and *sql.Tx.
+type sqliteQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// queryRecipes loads the recipes matched by where (applied to the recipes
+// table, may be empty) together with their ingredient references and
+// instructions.
+func queryRecipes(ctx context.Context, q sqliteQueryer, where string, args ...any) ([]model.RecipeReturnType, error) {
+	return queryRecipesClause(ctx, q, where+" ORDER BY rowid", args...)
+}
+
+// queryRecipesClause is queryRecipes with a complete WHERE ... ORDER BY ...
+// LIMIT clause, which also decides the order of the results.
+func queryRecipesClause(ctx context.Context, q sqliteQueryer, clause string, args ...any) ([]model.RecipeReturnType, error) {
+	rows, err := q.QueryContext(ctx,
+		"SELECT id, name, servings, prep_minutes, cook_minutes, difficulty, source_url, notes, calories_per_serving, version FROM recipes "+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		results model.RecipeResult
+		index   = make(map[string]int)
+	)
+	for rows.Next() {
+		var (
+			id     string
+			recipe model.RecipeReturnType
+		)
+		err := rows.Scan(&id, &recipe.Name, &recipe.Servings, &recipe.PrepMinutes, &recipe.CookMinutes,
+			&recipe.Difficulty, &recipe.SourceURL, &recipe.Notes, &recipe.CaloriesPerServing, &recipe.Version)
+		if err != nil {
+			return nil, err
+		}
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, fmt.Errorf("corrupt recipe id %q: %w", id, err)
+		}
+		recipe.ObjectID = objID
+		index[id] = len(results)
+		results = append(results, recipe)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	refs, err := q.QueryContext(ctx,
+		"SELECT recipe_id, ingredient_id, quantity, unit FROM recipe_ingredients WHERE recipe_id IN (SELECT id FROM recipes "+clause+") ORDER BY recipe_id, position",
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer refs.Close()
+
+	for refs.Next() {
+		var (
+			recipeID string
+			line     model.IngredientIDType
+		)
+		if err := refs.Scan(&recipeID, &line.ObjectID, &line.Quantity, &line.Unit); err != nil {
+			return nil, err
+		}
+		if i, ok := index[recipeID]; ok {
+			results[i].ID = append(results[i].ID, line)
+		}
+	}
+	if err := refs.Err(); err != nil {
+		return nil, err
+	}
+
+	steps, err := q.QueryContext(ctx,
+		"SELECT recipe_id, text FROM recipe_instructions WHERE recipe_id IN (SELECT id FROM recipes "+clause+") ORDER BY recipe_id, position",
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer steps.Close()
+
+	for steps.Next() {
+		var recipeID, step string
+		if err := steps.Scan(&recipeID, &step); err != nil {
+			return nil, err
+		}
+		if i, ok := index[recipeID]; ok {
+			results[i].Instructions = append(results[i].Instructions, step)
+		}
+	}
+	return results, steps.Err()
+}
+
+func (s *sqliteRecipeStore) FindByID(ctx context.Context, id primitive.ObjectID) (*model.RecipeReturnType, error) {
+	results, err := queryRecipes(ctx, s.db, "WHERE id = ?", id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrNotFound
+	}
+	return &results[0], nil
+}
+
+func (s *sqliteRecipeStore) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.RecipeReturnType, error) {
+	var results model.RecipeResult
+	for start := 0; start < len(ids); start += sqliteMaxInParams {
+		end := min(start+sqliteMaxInParams, len(ids))
+		args := make([]any, 0, end-start)
+		for _, id := range ids[start:end] {
+			args = append(args, id.Hex())
+		}
+
+		batch, err := queryRecipes(ctx, s.db, "WHERE id IN ("+placeholders(len(args))+")", args...)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, batch...)
+	}
+	return results, nil
+}
+
+func (s *sqliteRecipeStore) List(ctx context.Context) ([]model.RecipeReturnType, error) {
+	return queryRecipes(ctx, s.db, "")
+}
+
+func (s *sqliteRecipeStore) Query(ctx context.Context, q ListQuery) ([]model.RecipeReturnType, error) {
+	clause, args := sqliteListClause(q, "calories_per_serving")
+	return queryRecipesClause(ctx, s.db, clause, args...)
+}
+
+func (s *sqliteRecipeStore) InsertMany(ctx context.Context, recipes []model.RecipePostType) ([]primitive.ObjectID, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := make([]primitive.ObjectID, 0, len(recipes))
+	for _, recipe := range recipes {
+		id := primitive.NewObjectID()
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO recipes (id, name, servings, prep_minutes, cook_minutes, difficulty, source_url, notes, calories_per_serving, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1)",
+			id.Hex(), recipe.Name, recipe.Servings, recipe.PrepMinutes, recipe.CookMinutes, recipe.Difficulty, recipe.SourceURL, recipe.Notes, recipe.CaloriesPerServing)
+		if err != nil {
+			return nil, err
+		}
+		if err := insertRecipeIngredients(ctx, tx, id, recipe.Ingredients); err != nil {
+			return nil, err
+		}
+		if err := insertRecipeInstructions(ctx, tx, id, recipe.Instructions); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, tx.Commit()
+}
+
+func insertRecipeIngredients(ctx context.Context, tx *sql.Tx, recipeID primitive.ObjectID, ingredients []model.IngredientIDType) error {
+	for position, ingredient := range ingredients {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO recipe_ingredients (recipe_id, position, ingredient_id, quantity, unit) VALUES (?, ?, ?, ?, ?)",
+			recipeID.Hex(), position, ingredient.ObjectID, ingredient.Quantity, ingredient.Unit)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertRecipeInstructions(ctx context.Context, tx *sql.Tx, recipeID primitive.ObjectID, instructions []string) error {
+	for position, step := range instructions {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO recipe_instructions (recipe_id, position, text) VALUES (?, ?, ?)",
+			recipeID.Hex(), position, step)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqliteRecipeStore) UpdateByID(ctx context.Context, id primitive.ObjectID, version int64, update model.RecipeUpdate) (*model.RecipeReturnType, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var current int64
+	if err := tx.QueryRowContext(ctx, "SELECT version FROM recipes WHERE id = ?", id.Hex()).Scan(&current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if current != version {
+		return nil, ErrVersionConflict
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE recipes SET version = version + 1 WHERE id = ?", id.Hex()); err != nil {
+		return nil, err
+	}
+
+	if update.Name != nil {
+		if _, err := tx.ExecContext(ctx, "UPDATE recipes SET name = ? WHERE id = ?", *update.Name, id.Hex()); err != nil {
+			return nil, err
+		}
+	}
+	if update.Servings != nil {
+		if _, err := tx.ExecContext(ctx, "UPDATE recipes SET servings = ? WHERE id = ?", *update.Servings, id.Hex()); err != nil {
+			return nil, err
+		}
+	}
+	if update.PrepMinutes != nil {
+		if _, err := tx.ExecContext(ctx, "UPDATE recipes SET prep_minutes = ? WHERE id = ?", *update.PrepMinutes, id.Hex()); err != nil {
+			return nil, err
+		}
+	}
+	if update.CookMinutes != nil {
+		if _, err := tx.ExecContext(ctx, "UPDATE recipes SET cook_minutes = ? WHERE id = ?", *update.CookMinutes, id.Hex()); err != nil {
+			return nil, err
+		}
+	}
+	if update.Difficulty != nil {
+		if _, err := tx.ExecContext(ctx, "UPDATE recipes SET difficulty = ? WHERE id = ?", *update.Difficulty, id.Hex()); err != nil {
+			return nil, err
+		}
+	}
+	if update.SourceURL != nil {
+		if _, err := tx.ExecContext(ctx, "UPDATE recipes SET source_url = ? WHERE id = ?", *update.SourceURL, id.Hex()); err != nil {
+			return nil, err
+		}
+	}
+	if update.Notes != nil {
+		if _, err := tx.ExecContext(ctx, "UPDATE recipes SET notes = ? WHERE id = ?", *update.Notes, id.Hex()); err != nil {
+			return nil, err
+		}
+	}
+	if update.CaloriesPerServing != nil {
+		if _, err := tx.ExecContext(ctx, "UPDATE recipes SET calories_per_serving = ? WHERE id = ?", *update.CaloriesPerServing, id.Hex()); err != nil {
+			return nil, err
+		}
+	}
+	if update.Ingredients != nil {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM recipe_ingredients WHERE recipe_id = ?", id.Hex()); err != nil {
+			return nil, err
+		}
+		if err := insertRecipeIngredients(ctx, tx, id, *update.Ingredients); err != nil {
+			return nil, err
+		}
+	}
+	if update.Instructions != nil {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM recipe_instructions WHERE recipe_id = ?", id.Hex()); err != nil {
+			return nil, err
+		}
+		if err := insertRecipeInstructions(ctx, tx, id, *update.Instructions); err != nil {
+			return nil, err
+		}
+	}
+
+	results, err := queryRecipes(ctx, tx, "WHERE id = ?", id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &results[0], nil
+}
+
+func (s *sqliteRecipeStore) DeleteByID(ctx context.Context, id primitive.ObjectID, version int64) error {
+	return deleteVersioned(ctx, s.db, "recipes", id, version)
+}
+
+func (s *sqliteRecipeStore) SetCalories(ctx context.Context, id primitive.ObjectID, calories int) error {
+	result, err := s.db.ExecContext(ctx, "UPDATE recipes SET calories_per_serving = ? WHERE id = ?", calories, id.Hex())
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// referencingRecipes restricts a recipe query to recipes that use an ingredient.
+const referencingRecipes = "WHERE id IN (SELECT recipe_id FROM recipe_ingredients WHERE ingredient_id = ?)"
+
+func (s *sqliteRecipeStore) FindByIngredient(ctx context.Context, ingredientID primitive.ObjectID) ([]model.RecipeReturnType, error) {
+	return queryRecipes(ctx, s.db, referencingRecipes, ingredientID.Hex())
+}
+
+func (s *sqliteRecipeStore) DeleteByIngredient(ctx context.Context, ingredientID primitive.ObjectID) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM recipes "+referencingRecipes, ingredientID.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *sqliteRecipeStore) RemoveIngredient(ctx context.Context, ingredientID primitive.ObjectID) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var modified int64
+	err = tx.QueryRowContext(ctx,
+		"SELECT COUNT(DISTINCT recipe_id) FROM recipe_ingredients WHERE ingredient_id = ?",
+		ingredientID.Hex()).Scan(&modified)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE recipes SET version = version + 1 "+referencingRecipes, ingredientID.Hex()); err != nil {
+		return 0, err
+	}
+	// Positions may be left with gaps; they are only used for ordering.
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recipe_ingredients WHERE ingredient_id = ?", ingredientID.Hex()); err != nil {
+		return 0, err
+	}
+	return modified, tx.Commit()
+}
+
+type sqlitePantryStore struct {
+	db *sql.DB
+}
+
+// Pantry dates are stored as Unix seconds.
+const pantryColumns = "id, user_id, ingredient_id, quantity, unit, purchased_at, expires_at"
+
+func scanPantryItem(row rowScanner) (model.PantryItem, error) {
+	var (
+		item        model.PantryItem
+		id          string
+		purchasedAt int64
+		expiresAt   sql.NullInt64
+	)
+	err := row.Scan(&id, &item.UserID, &item.IngredientID, &item.Quantity, &item.Unit, &purchasedAt, &expiresAt)
+	if err != nil {
+		return model.PantryItem{}, err
+	}
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return model.PantryItem{}, fmt.Errorf("corrupt pantry item id %q: %w", id, err)
+	}
+	item.ObjectID = objID
+	item.PurchasedAt = time.Unix(purchasedAt, 0).UTC()
+	if expiresAt.Valid {
+		t := time.Unix(expiresAt.Int64, 0).UTC()
+		item.ExpiresAt = &t
+	}
+	return item, nil
+}
+
+// pantryExpiry returns the expires_at column value of an item.
+func pantryExpiry(item model.PantryItem) any {
+	if item.ExpiresAt == nil {
+		return nil
+	}
+	return item.ExpiresAt.Unix()
+}
+
+// queryPantryItems loads the pantry items matched by clause.
+func queryPantryItems(ctx context.Context, q sqliteQueryer, clause string, args ...any) ([]model.PantryItem, error) {
+	rows, err := q.QueryContext(ctx, "SELECT "+pantryColumns+" FROM pantry_items "+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]model.PantryItem, 0)
+	for rows.Next() {
+		item, err := scanPantryItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, item)
+	}
+	return results, rows.Err()
+}
+
+func (s *sqlitePantryStore) FindByID(ctx context.Context, id primitive.ObjectID) (*model.PantryItem, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+pantryColumns+" FROM pantry_items WHERE id = ?", id.Hex())
+	item, err := scanPantryItem(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (s *sqlitePantryStore) ListByUser(ctx context.Context, userID string) ([]model.PantryItem, error) {
+	return queryPantryItems(ctx, s.db, "WHERE user_id = ? ORDER BY rowid", userID)
+}
+
+func (s *sqlitePantryStore) ExpiringBefore(ctx context.Context, userID string, before time.Time) ([]model.PantryItem, error) {
+	return queryPantryItems(ctx, s.db, "WHERE user_id = ? AND expires_at < ? ORDER BY expires_at, rowid", userID, before.Unix())
+}
+
+func (s *sqlitePantryStore) InsertMany(ctx context.Context, items []model.PantryItem) ([]primitive.ObjectID, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := make([]primitive.ObjectID, 0, len(items))
+	for _, item := range items {
+		if item.ObjectID.IsZero() {
+			item.ObjectID = primitive.NewObjectID()
+		}
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO pantry_items ("+pantryColumns+") VALUES (?, ?, ?, ?, ?, ?, ?)",
+			item.ObjectID.Hex(), item.UserID, item.IngredientID, item.Quantity, item.Unit, item.PurchasedAt.Unix(), pantryExpiry(item))
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, item.ObjectID)
+	}
+	return ids, tx.Commit()
+}
+
+func (s *sqlitePantryStore) UpdateByID(ctx context.Context, id primitive.ObjectID, update model.PantryItemUpdate) (*model.PantryItem, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	item, err := scanPantryItem(tx.QueryRowContext(ctx, "SELECT "+pantryColumns+" FROM pantry_items WHERE id = ?", id.Hex()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	update.Apply(&item)
+	_, err = tx.ExecContext(ctx,
+		"UPDATE pantry_items SET quantity = ?, unit = ?, purchased_at = ?, expires_at = ? WHERE id = ?",
+		item.Quantity, item.Unit, item.PurchasedAt.Unix(), pantryExpiry(item), id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return &item, tx.Commit()
+}
+
+func (s *sqlitePantryStore) DeleteByID(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM pantry_items WHERE id = ?", id.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+type sqliteShoppingListStore struct {
+	db *sql.DB
+}
+
+// queryShoppingLists loads the shopping lists matched by where (applied to the
+// shopping_lists table, may be empty) together with their members and items.
+// Members are kept in a table of their own so that ListByUser can use an
+// index; UpdatedAt is stored as Unix milliseconds.
+func queryShoppingLists(ctx context.Context, q sqliteQueryer, where string, args ...any) ([]model.ShoppingList, error) {
+	rows, err := q.QueryContext(ctx, "SELECT id, name, owner_id, version, updated_at FROM shopping_lists "+where+" ORDER BY rowid", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		results = make([]model.ShoppingList, 0)
+		index   = make(map[string]int)
+	)
+	for rows.Next() {
+		var (
+			id        string
+			updatedAt int64
+			list      = model.ShoppingList{Members: []string{}, Items: []model.ShoppingListItem{}}
+		)
+		if err := rows.Scan(&id, &list.Name, &list.OwnerID, &list.Version, &updatedAt); err != nil {
+			return nil, err
+		}
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, fmt.Errorf("corrupt shopping list id %q: %w", id, err)
+		}
+		list.ObjectID = objID
+		list.UpdatedAt = time.UnixMilli(updatedAt).UTC()
+		index[id] = len(results)
+		results = append(results, list)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	members, err := q.QueryContext(ctx,
+		"SELECT list_id, user_id FROM shopping_list_members WHERE list_id IN (SELECT id FROM shopping_lists "+where+") ORDER BY rowid",
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer members.Close()
+
+	for members.Next() {
+		var listID, userID string
+		if err := members.Scan(&listID, &userID); err != nil {
+			return nil, err
+		}
+		if i, ok := index[listID]; ok {
+			results[i].Members = append(results[i].Members, userID)
+		}
+	}
+	if err := members.Err(); err != nil {
+		return nil, err
+	}
+
+	items, err := q.QueryContext(ctx,
+		"SELECT list_id, item_id, ingredient_id, name, category, quantity, unit, checked FROM shopping_list_items "+
+			"WHERE list_id IN (SELECT id FROM shopping_lists "+where+") ORDER BY list_id, position",
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer items.Close()
+
+	for items.Next() {
+		var (
+			listID, itemID string
+			item           model.ShoppingListItem
+		)
+		err := items.Scan(&listID, &itemID, &item.IngredientID, &item.Name, &item.Category, &item.Quantity, &item.Unit, &item.Checked)
+		if err != nil {
+			return nil, err
+		}
+		if item.ItemID, err = primitive.ObjectIDFromHex(itemID); err != nil {
+			return nil, fmt.Errorf("corrupt shopping list item id %q: %w", itemID, err)
+		}
+		if i, ok := index[listID]; ok {
+			results[i].Items = append(results[i].Items, item)
+		}
+	}
+	return results, items.Err()
+}
+
+func insertShoppingListMembers(ctx context.Context, tx *sql.Tx, listID primitive.ObjectID, members []string) error {
+	for _, userID := range members {
+		_, err := tx.ExecContext(ctx,
+			"INSERT OR IGNORE INTO shopping_list_members (list_id, user_id) VALUES (?, ?)", listID.Hex(), userID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertShoppingListItems appends items to a list, generating their IDs.
+func insertShoppingListItems(ctx context.Context, tx *sql.Tx, listID primitive.ObjectID, items []model.ShoppingListItem) error {
+	var next int
+	err := tx.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(position) + 1, 0) FROM shopping_list_items WHERE list_id = ?", listID.Hex()).Scan(&next)
+	if err != nil {
+		return err
+	}
+	for i, item := range items {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO shopping_list_items (item_id, list_id, position, ingredient_id, name, category, quantity, unit, checked) "+
+				"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			primitive.NewObjectID().Hex(), listID.Hex(), next+i, item.IngredientID, item.Name, item.Category, item.Quantity, item.Unit, item.Checked)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqliteShoppingListStore) FindByID(ctx context.Context, id primitive.ObjectID) (*model.ShoppingList, error) {
+	results, err := queryShoppingLists(ctx, s.db, "WHERE id = ?", id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrNotFound
+	}
+	return &results[0], nil
+}
+
+func (s *sqliteShoppingListStore) ListByUser(ctx context.Context, userID string) ([]model.ShoppingList, error) {
+	return queryShoppingLists(ctx, s.db,
+		"WHERE owner_id = ? OR id IN (SELECT list_id FROM shopping_list_members WHERE user_id = ?)", userID, userID)
+}
+
+func (s *sqliteShoppingListStore) Insert(ctx context.Context, list model.ShoppingList) (*model.ShoppingList, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	id := primitive.NewObjectID()
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO shopping_lists (id, name, owner_id, version, updated_at) VALUES (?, ?, ?, 1, ?)",
+		id.Hex(), list.Name, list.OwnerID, time.Now().UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	if err := insertShoppingListMembers(ctx, tx, id, list.Members); err != nil {
+		return nil, err
+	}
+	if err := insertShoppingListItems(ctx, tx, id, list.Items); err != nil {
+		return nil, err
+	}
+	return s.commit(ctx, tx, id)
+}
+
+// commit reads back the list with the given ID and commits tx.
+func (s *sqliteShoppingListStore) commit(ctx context.Context, tx *sql.Tx, id primitive.ObjectID) (*model.ShoppingList, error) {
+	results, err := queryShoppingLists(ctx, tx, "WHERE id = ?", id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrNotFound
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &results[0], nil
+}
+
+// modify runs change on the list with the given ID within a transaction and
+// stores the list as its next version, unless change fails.
+func (s *sqliteShoppingListStore) modify(ctx context.Context, id primitive.ObjectID, change func(tx *sql.Tx) error) (*model.ShoppingList, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE shopping_lists SET version = version + 1, updated_at = ? WHERE id = ?", time.Now().UnixMilli(), id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrNotFound
+	}
+	if err := change(tx); err != nil {
+		return nil, err
+	}
+	return s.commit(ctx, tx, id)
+}
+
+func (s *sqliteShoppingListStore) UpdateByID(ctx context.Context, id primitive.ObjectID, update model.ShoppingListUpdate) (*model.ShoppingList, error) {
+	return s.modify(ctx, id, func(tx *sql.Tx) error {
+		if update.Name != nil {
+			if _, err := tx.ExecContext(ctx, "UPDATE shopping_lists SET name = ? WHERE id = ?", *update.Name, id.Hex()); err != nil {
+				return err
+			}
+		}
+		if update.Members != nil {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM shopping_list_members WHERE list_id = ?", id.Hex()); err != nil {
+				return err
+			}
+			return insertShoppingListMembers(ctx, tx, id, *update.Members)
+		}
+		return nil
+	})
+}
+
+func (s *sqliteShoppingListStore) DeleteByID(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM shopping_lists WHERE id = ?", id.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *sqliteShoppingListStore) AddItems(ctx context.Context, id primitive.ObjectID, items []model.ShoppingListItem) (*model.ShoppingList, error) {
+	return s.modify(ctx, id, func(tx *sql.Tx) error {
+		return insertShoppingListItems(ctx, tx, id, items)
+	})
+}
+
+func (s *sqliteShoppingListStore) UpdateItem(ctx context.Context, id, itemID primitive.ObjectID, update model.ShoppingListItemUpdate) (*model.ShoppingList, error) {
+	return s.modify(ctx, id, func(tx *sql.Tx) error {
+		var item model.ShoppingListItem
+		err := tx.QueryRowContext(ctx,
+			"SELECT name, quantity, unit, checked FROM shopping_list_items WHERE list_id = ? AND item_id = ?",
+			id.Hex(), itemID.Hex()).Scan(&item.Name, &item.Quantity, &item.Unit, &item.Checked)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+		update.Apply(&item)
+		_, err = tx.ExecContext(ctx,
+			"UPDATE shopping_list_items SET name = ?, quantity = ?, unit = ?, checked = ? WHERE item_id = ?",
+			item.Name, item.Quantity, item.Unit, item.Checked, itemID.Hex())
+		return err
+	})
+}
+
+func (s *sqliteShoppingListStore) RemoveItem(ctx context.Context, id, itemID primitive.ObjectID) (*model.ShoppingList, error) {
+	return s.modify(ctx, id, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, "DELETE FROM shopping_list_items WHERE list_id = ? AND item_id = ?", id.Hex(), itemID.Hex())
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+type sqliteMealPlanStore struct {
+	db *sql.DB
+}
+
+// Meal plan dates are stored as Unix seconds.
+const mealPlanColumns = "id, user_id, date, slot, recipe_id, servings"
+
+func scanMealPlanEntry(row rowScanner) (model.MealPlanEntry, error) {
+	var (
+		entry model.MealPlanEntry
+		id    string
+		date  int64
+	)
+	if err := row.Scan(&id, &entry.UserID, &date, &entry.Slot, &entry.RecipeID, &entry.Servings); err != nil {
+		return model.MealPlanEntry{}, err
+	}
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return model.MealPlanEntry{}, fmt.Errorf("corrupt meal plan entry id %q: %w", id, err)
+	}
+	entry.ObjectID = objID
+	entry.Date = time.Unix(date, 0).UTC()
+	return entry, nil
+}
+
+func (s *sqliteMealPlanStore) FindByID(ctx context.Context, id primitive.ObjectID) (*model.MealPlanEntry, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+mealPlanColumns+" FROM meal_plan_entries WHERE id = ?", id.Hex())
+	entry, err := scanMealPlanEntry(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (s *sqliteMealPlanStore) ListByUser(ctx context.Context, userID string, from, to time.Time) ([]model.MealPlanEntry, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+mealPlanColumns+" FROM meal_plan_entries WHERE user_id = ? AND date >= ? AND date < ? ORDER BY date, rowid",
+		userID, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]model.MealPlanEntry, 0)
+	for rows.Next() {
+		entry, err := scanMealPlanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, entry)
+	}
+	return results, rows.Err()
+}
+
+func (s *sqliteMealPlanStore) InsertMany(ctx context.Context, entries []model.MealPlanEntry) ([]primitive.ObjectID, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := make([]primitive.ObjectID, 0, len(entries))
+	for _, entry := range entries {
+		if entry.ObjectID.IsZero() {
+			entry.ObjectID = primitive.NewObjectID()
+		}
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO meal_plan_entries ("+mealPlanColumns+") VALUES (?, ?, ?, ?, ?, ?)",
+			entry.ObjectID.Hex(), entry.UserID, entry.Date.Unix(), entry.Slot, entry.RecipeID, entry.Servings)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, entry.ObjectID)
+	}
+	return ids, tx.Commit()
+}
+
+func (s *sqliteMealPlanStore) UpdateByID(ctx context.Context, id primitive.ObjectID, update model.MealPlanEntryUpdate) (*model.MealPlanEntry, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	entry, err := scanMealPlanEntry(tx.QueryRowContext(ctx, "SELECT "+mealPlanColumns+" FROM meal_plan_entries WHERE id = ?", id.Hex()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	update.Apply(&entry)
+	_, err = tx.ExecContext(ctx,
+		"UPDATE meal_plan_entries SET date = ?, slot = ?, recipe_id = ?, servings = ? WHERE id = ?",
+		entry.Date.Unix(), entry.Slot, entry.RecipeID, entry.Servings, id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return &entry, tx.Commit()
+}
+
+func (s *sqliteMealPlanStore) DeleteByID(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM meal_plan_entries WHERE id = ?", id.Hex())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *sqliteMealPlanStore) DeleteByUser(ctx context.Context, userID string, from, to time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM meal_plan_entries WHERE user_id = ? AND date >= ? AND date < ?", userID, from.Unix(), to.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,21 @@
+//go:build cgo
+
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// The SQLite driver needs cgo, so this only runs in builds with cgo enabled.
+func TestSQLiteStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		s, err := NewSQLiteStore(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatalf("NewSQLiteStore: %v", err)
+		}
+		t.Cleanup(func() { _ = s.Close(context.Background()) })
+		return s
+	})
+}
@@ -1,3 +1,148 @@
 package store
 
-// TODO: Implement store logic
+import (
+	"context"
+	"dynamicrecipes/pkg/model"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotFound is returned when no document matches the requested ID.
+var ErrNotFound = errors.New("store: document not found")
+
+// ErrVersionConflict is returned by updates when the document exists but is no
+// longer at the version the caller based its changes on.
+var ErrVersionConflict = errors.New("store: version conflict")
+
+// Store is the persistence boundary of the service. Handlers and repositories
+// only talk to a Store, so the backing database can be swapped without touching
+// the HTTP layer.
+type Store interface {
+	Ingredients() IngredientStore
+	Recipes() RecipeStore
+	Pantry() PantryStore
+	ShoppingLists() ShoppingListStore
+	MealPlans() MealPlanStore
+
+	// Close releases any connection held by the backend.
+	Close(ctx context.Context) error
+}
+
+// IngredientStore persists model.Ingredient documents.
+type IngredientStore interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (*model.Ingredient, error)
+	// FindByIDs fetches all ingredients with the given IDs in a single round
+	// trip. IDs that do not exist are simply absent from the result.
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.Ingredient, error)
+	List(ctx context.Context) ([]model.Ingredient, error)
+	// Query returns the page of ingredients selected by q, in its order.
+	Query(ctx context.Context, q ListQuery) ([]model.Ingredient, error)
+	InsertMany(ctx context.Context, ingredients []model.Ingredient) ([]primitive.ObjectID, error)
+	// UpdateByID applies the non-nil fields of update if the ingredient is
+	// still at version, increments the version and returns the updated
+	// ingredient. It returns ErrNotFound when no ingredient has the given ID
+	// and ErrVersionConflict when it is at another version.
+	UpdateByID(ctx context.Context, id primitive.ObjectID, version int64, update model.IngredientUpdate) (*model.Ingredient, error)
+	// FindByName returns the first ingredient with the given name, or ErrNotFound.
+	FindByName(ctx context.Context, name string) (*model.Ingredient, error)
+	// DeleteByID removes the ingredient if it is still at version. Like
+	// UpdateByID, it returns ErrNotFound when no ingredient has the given ID
+	// and ErrVersionConflict when it is at another version.
+	DeleteByID(ctx context.Context, id primitive.ObjectID, version int64) error
+}
+
+// RecipeStore persists recipes in their stored form, i.e. with ingredient
+// references rather than resolved ingredients.
+type RecipeStore interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (*model.RecipeReturnType, error)
+	// FindByIDs fetches all recipes with the given IDs in a single round trip.
+	// IDs that do not exist are simply absent from the result.
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.RecipeReturnType, error)
+	List(ctx context.Context) ([]model.RecipeReturnType, error)
+	// Query returns the page of recipes selected by q, in its order.
+	// Calories are the stored CaloriesPerServing.
+	Query(ctx context.Context, q ListQuery) ([]model.RecipeReturnType, error)
+	InsertMany(ctx context.Context, recipes []model.RecipePostType) ([]primitive.ObjectID, error)
+	// UpdateByID is the recipe counterpart of IngredientStore.UpdateByID.
+	UpdateByID(ctx context.Context, id primitive.ObjectID, version int64, update model.RecipeUpdate) (*model.RecipeReturnType, error)
+	// DeleteByID is the recipe counterpart of IngredientStore.DeleteByID.
+	DeleteByID(ctx context.Context, id primitive.ObjectID, version int64) error
+	// SetCalories stores the calories per serving of a recipe without
+	// incrementing its version, since they only follow from its ingredients.
+	// It returns ErrNotFound when no recipe has the given ID.
+	SetCalories(ctx context.Context, id primitive.ObjectID, calories int) error
+
+	// FindByIngredient returns the recipes that reference the given ingredient.
+	FindByIngredient(ctx context.Context, ingredientID primitive.ObjectID) ([]model.RecipeReturnType, error)
+	// DeleteByIngredient removes every recipe that references the given
+	// ingredient and reports how many were deleted.
+	DeleteByIngredient(ctx context.Context, ingredientID primitive.ObjectID) (int64, error)
+	// RemoveIngredient drops the given ingredient from every recipe that
+	// references it, incrementing their versions, and reports how many
+	// recipes were modified.
+	RemoveIngredient(ctx context.Context, ingredientID primitive.ObjectID) (int64, error)
+}
+
+// PantryStore persists the items of every user's pantry.
+type PantryStore interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (*model.PantryItem, error)
+	// ListByUser returns the items of a user's pantry in insertion order.
+	ListByUser(ctx context.Context, userID string) ([]model.PantryItem, error)
+	// ExpiringBefore returns the items of a user's pantry that expire before
+	// the given time, including expired ones, soonest first.
+	ExpiringBefore(ctx context.Context, userID string, before time.Time) ([]model.PantryItem, error)
+	InsertMany(ctx context.Context, items []model.PantryItem) ([]primitive.ObjectID, error)
+	// UpdateByID applies the non-nil fields of update and returns the updated
+	// item, or ErrNotFound.
+	UpdateByID(ctx context.Context, id primitive.ObjectID, update model.PantryItemUpdate) (*model.PantryItem, error)
+	// DeleteByID removes at most one item and reports how many were deleted.
+	DeleteByID(ctx context.Context, id primitive.ObjectID) (int64, error)
+}
+
+// ShoppingListStore persists shopping lists together with their items. Every
+// method that changes a list increments its version and sets its UpdatedAt,
+// and item changes are applied atomically so that concurrent check-offs do
+// not overwrite each other.
+type ShoppingListStore interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (*model.ShoppingList, error)
+	// ListByUser returns the lists a user owns or is a member of, in
+	// insertion order.
+	ListByUser(ctx context.Context, userID string) ([]model.ShoppingList, error)
+	// Insert stores a new list, generating IDs for it and its items.
+	Insert(ctx context.Context, list model.ShoppingList) (*model.ShoppingList, error)
+	// UpdateByID applies the non-nil fields of update and returns the updated
+	// list, or ErrNotFound.
+	UpdateByID(ctx context.Context, id primitive.ObjectID, update model.ShoppingListUpdate) (*model.ShoppingList, error)
+	// DeleteByID removes at most one list and reports how many were deleted.
+	DeleteByID(ctx context.Context, id primitive.ObjectID) (int64, error)
+
+	// AddItems appends items to a list, generating their IDs, and returns the
+	// updated list, or ErrNotFound.
+	AddItems(ctx context.Context, id primitive.ObjectID, items []model.ShoppingListItem) (*model.ShoppingList, error)
+	// UpdateItem applies the non-nil fields of update to one item and
+	// returns the updated list. It returns ErrNotFound when the list or the
+	// item does not exist.
+	UpdateItem(ctx context.Context, id, itemID primitive.ObjectID, update model.ShoppingListItemUpdate) (*model.ShoppingList, error)
+	// RemoveItem removes one item and returns the updated list. It returns
+	// ErrNotFound when the list or the item does not exist.
+	RemoveItem(ctx context.Context, id, itemID primitive.ObjectID) (*model.ShoppingList, error)
+}
+
+// MealPlanStore persists the meal plan entries of every user.
+type MealPlanStore interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (*model.MealPlanEntry, error)
+	// ListByUser returns the entries of a user's meal plan dated from from
+	// up to but excluding to, by date and then in insertion order.
+	ListByUser(ctx context.Context, userID string, from, to time.Time) ([]model.MealPlanEntry, error)
+	InsertMany(ctx context.Context, entries []model.MealPlanEntry) ([]primitive.ObjectID, error)
+	// UpdateByID applies the non-nil fields of update and returns the updated
+	// entry, or ErrNotFound.
+	UpdateByID(ctx context.Context, id primitive.ObjectID, update model.MealPlanEntryUpdate) (*model.MealPlanEntry, error)
+	// DeleteByID removes at most one entry and reports how many were deleted.
+	DeleteByID(ctx context.Context, id primitive.ObjectID) (int64, error)
+	// DeleteByUser removes the entries of a user's meal plan dated from from
+	// up to but excluding to, and reports how many were deleted.
+	DeleteByUser(ctx context.Context, userID string, from, to time.Time) (int64, error)
+}
@@ -0,0 +1,319 @@
+package store
+
+import (
+	"context"
+	"dynamicrecipes/pkg/model"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testStore runs the behaviour every Store backend must share against the
+// stores returned by newStore, which must be empty.
+func testStore(t *testing.T, newStore func(t *testing.T) Store) {
+	t.Run("IngredientFindByIDs", func(t *testing.T) { testIngredientFindByIDs(t, newStore(t)) })
+	t.Run("IngredientNotFound", func(t *testing.T) { testIngredientNotFound(t, newStore(t)) })
+	t.Run("IngredientVersionedUpdate", func(t *testing.T) { testIngredientVersionedUpdate(t, newStore(t)) })
+	t.Run("IngredientVersionedDelete", func(t *testing.T) { testIngredientVersionedDelete(t, newStore(t)) })
+	t.Run("RecipeFindByIDs", func(t *testing.T) { testRecipeFindByIDs(t, newStore(t)) })
+	t.Run("RecipeNotFound", func(t *testing.T) { testRecipeNotFound(t, newStore(t)) })
+	t.Run("RecipeVersionedUpdate", func(t *testing.T) { testRecipeVersionedUpdate(t, newStore(t)) })
+	t.Run("RecipeVersionedDelete", func(t *testing.T) { testRecipeVersionedDelete(t, newStore(t)) })
+	t.Run("RecipeRemoveIngredient", func(t *testing.T) { testRecipeRemoveIngredient(t, newStore(t)) })
+	t.Run("RecipeQueryByCalories", func(t *testing.T) { testRecipeQueryByCalories(t, newStore(t)) })
+}
+
+func insertIngredients(t *testing.T, s Store, names ...string) []primitive.ObjectID {
+	t.Helper()
+	ingredients := make([]model.Ingredient, 0, len(names))
+	for _, name := range names {
+		ingredients = append(ingredients, model.Ingredient{Name: name, Calories: 4})
+	}
+	ids, err := s.Ingredients().InsertMany(context.Background(), ingredients)
+	if err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+	if len(ids) != len(names) {
+		t.Fatalf("InsertMany returned %d IDs, want %d", len(ids), len(names))
+	}
+	return ids
+}
+
+func insertRecipe(t *testing.T, s Store, name string, ingredients ...primitive.ObjectID) primitive.ObjectID {
+	t.Helper()
+	recipe := model.RecipePostType{Name: name, Servings: 2}
+	for _, id := range ingredients {
+		recipe.Ingredients = append(recipe.Ingredients, model.IngredientIDType{ObjectID: id.Hex(), Quantity: 100, Unit: "g"})
+	}
+	ids, err := s.Recipes().InsertMany(context.Background(), []model.RecipePostType{recipe})
+	if err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+	return ids[0]
+}
+
+func testIngredientFindByIDs(t *testing.T, s Store) {
+	ctx := context.Background()
+	ids := insertIngredients(t, s, "flour", "egg", "milk")
+
+	found, err := s.Ingredients().FindByIDs(ctx, []primitive.ObjectID{ids[2], primitive.NewObjectID(), ids[0]})
+	if err != nil {
+		t.Fatalf("FindByIDs: %v", err)
+	}
+	names := make(map[string]bool)
+	for _, ingredient := range found {
+		names[ingredient.Name] = true
+		if ingredient.Version != 1 {
+			t.Errorf("%s has version %d, want 1", ingredient.Name, ingredient.Version)
+		}
+	}
+	if len(found) != 2 || !names["flour"] || !names["milk"] {
+		t.Errorf("FindByIDs found %v, want flour and milk only", found)
+	}
+
+	found, err = s.Ingredients().FindByIDs(ctx, nil)
+	if err != nil {
+		t.Fatalf("FindByIDs(nil): %v", err)
+	}
+	if len(found) != 0 {
+		t.Errorf("FindByIDs(nil) found %v, want nothing", found)
+	}
+}
+
+func testIngredientNotFound(t *testing.T, s Store) {
+	ctx := context.Background()
+	missing := primitive.NewObjectID()
+
+	if _, err := s.Ingredients().FindByID(ctx, missing); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindByID of a missing ingredient: got %v, want ErrNotFound", err)
+	}
+	if _, err := s.Ingredients().FindByName(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindByName of a missing ingredient: got %v, want ErrNotFound", err)
+	}
+	name := "renamed"
+	if _, err := s.Ingredients().UpdateByID(ctx, missing, 1, model.IngredientUpdate{Name: &name}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateByID of a missing ingredient: got %v, want ErrNotFound", err)
+	}
+	if err := s.Ingredients().DeleteByID(ctx, missing, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteByID of a missing ingredient: got %v, want ErrNotFound", err)
+	}
+}
+
+func testIngredientVersionedUpdate(t *testing.T, s Store) {
+	ctx := context.Background()
+	id := insertIngredients(t, s, "flour")[0]
+
+	name := "wholemeal flour"
+	updated, err := s.Ingredients().UpdateByID(ctx, id, 1, model.IngredientUpdate{Name: &name})
+	if err != nil {
+		t.Fatalf("UpdateByID at the current version: %v", err)
+	}
+	if updated.Name != name || updated.Version != 2 || updated.Calories != 4 {
+		t.Errorf("UpdateByID returned %+v, want name %q, version 2 and calories untouched", updated, name)
+	}
+
+	stale := "rye flour"
+	if _, err := s.Ingredients().UpdateByID(ctx, id, 1, model.IngredientUpdate{Name: &stale}); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("UpdateByID at a stale version: got %v, want ErrVersionConflict", err)
+	}
+	current, err := s.Ingredients().FindByID(ctx, id)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if current.Name != name || current.Version != 2 {
+		t.Errorf("stale update changed the ingredient to %+v", current)
+	}
+}
+
+func testRecipeFindByIDs(t *testing.T, s Store) {
+	ctx := context.Background()
+	ingredients := insertIngredients(t, s, "flour", "egg")
+	pancakes := insertRecipe(t, s, "pancakes", ingredients...)
+	bread := insertRecipe(t, s, "bread", ingredients[0])
+	insertRecipe(t, s, "omelette", ingredients[1])
+
+	found, err := s.Recipes().FindByIDs(ctx, []primitive.ObjectID{bread, primitive.NewObjectID(), pancakes})
+	if err != nil {
+		t.Fatalf("FindByIDs: %v", err)
+	}
+	byName := make(map[string]model.RecipeReturnType)
+	for _, recipe := range found {
+		byName[recipe.Name] = recipe
+	}
+	if len(found) != 2 || len(byName["pancakes"].ID) != 2 || len(byName["bread"].ID) != 1 {
+		t.Fatalf("FindByIDs found %+v, want pancakes with 2 and bread with 1 ingredient", found)
+	}
+	line := byName["pancakes"].ID[0]
+	if line.ObjectID != ingredients[0].Hex() || line.Quantity != 100 || line.Unit != "g" {
+		t.Errorf("first line of pancakes is %+v, want 100 g of flour", line)
+	}
+}
+
+func testRecipeNotFound(t *testing.T, s Store) {
+	ctx := context.Background()
+	missing := primitive.NewObjectID()
+
+	if _, err := s.Recipes().FindByID(ctx, missing); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindByID of a missing recipe: got %v, want ErrNotFound", err)
+	}
+	name := "renamed"
+	if _, err := s.Recipes().UpdateByID(ctx, missing, 1, model.RecipeUpdate{Name: &name}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateByID of a missing recipe: got %v, want ErrNotFound", err)
+	}
+	if err := s.Recipes().DeleteByID(ctx, missing, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteByID of a missing recipe: got %v, want ErrNotFound", err)
+	}
+}
+
+func testRecipeVersionedUpdate(t *testing.T, s Store) {
+	ctx := context.Background()
+	ingredients := insertIngredients(t, s, "flour", "egg")
+	id := insertRecipe(t, s, "pancakes", ingredients[0])
+
+	servings := 4
+	lines := []model.IngredientIDType{{ObjectID: ingredients[1].Hex(), Quantity: 2, Unit: "pc"}}
+	updated, err := s.Recipes().UpdateByID(ctx, id, 1, model.RecipeUpdate{Servings: &servings, Ingredients: &lines})
+	if err != nil {
+		t.Fatalf("UpdateByID at the current version: %v", err)
+	}
+	if updated.Name != "pancakes" || updated.Servings != 4 || updated.Version != 2 || len(updated.ID) != 1 || updated.ID[0].ObjectID != ingredients[1].Hex() {
+		t.Errorf("UpdateByID returned %+v, want 4 servings of egg pancakes at version 2", updated)
+	}
+
+	name := "crepes"
+	if _, err := s.Recipes().UpdateByID(ctx, id, 1, model.RecipeUpdate{Name: &name}); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("UpdateByID at a stale version: got %v, want ErrVersionConflict", err)
+	}
+	if _, err := s.Recipes().UpdateByID(ctx, id, 2, model.RecipeUpdate{Name: &name}); err != nil {
+		t.Errorf("UpdateByID at the new version: %v", err)
+	}
+}
+
+func testRecipeRemoveIngredient(t *testing.T, s Store) {
+	ctx := context.Background()
+	ingredients := insertIngredients(t, s, "flour", "egg")
+	pancakes := insertRecipe(t, s, "pancakes", ingredients...)
+	bread := insertRecipe(t, s, "bread", ingredients[0])
+
+	modified, err := s.Recipes().RemoveIngredient(ctx, ingredients[1])
+	if err != nil {
+		t.Fatalf("RemoveIngredient: %v", err)
+	}
+	if modified != 1 {
+		t.Errorf("RemoveIngredient modified %d recipes, want 1", modified)
+	}
+	recipe, err := s.Recipes().FindByID(ctx, pancakes)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if len(recipe.ID) != 1 || recipe.Version != 2 {
+		t.Errorf("pancakes are %+v, want only flour at version 2", recipe)
+	}
+	recipe, err = s.Recipes().FindByID(ctx, bread)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if recipe.Version != 1 {
+		t.Errorf("bread is at version %d, want 1: it does not use egg", recipe.Version)
+	}
+}
+
+func testRecipeQueryByCalories(t *testing.T, s Store) {
+	ctx := context.Background()
+	recipes := []model.RecipePostType{
+		{Name: "broth", CaloriesPerServing: 200},
+		{Name: "eclairs", CaloriesPerServing: 100},
+		{Name: "crepes", CaloriesPerServing: 150},
+		{Name: "stew"},
+	}
+	ids, err := s.Recipes().InsertMany(ctx, recipes)
+	if err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+	// Setting calories leaves the version alone, and updates can set them
+	// along with the lines they follow from.
+	if err := s.Recipes().SetCalories(ctx, ids[3], 150); err != nil {
+		t.Fatalf("SetCalories: %v", err)
+	}
+	calories := 250
+	if _, err := s.Recipes().UpdateByID(ctx, ids[0], 1, model.RecipeUpdate{CaloriesPerServing: &calories}); err != nil {
+		t.Fatalf("UpdateByID: %v", err)
+	}
+	if err := s.Recipes().SetCalories(ctx, primitive.NewObjectID(), 100); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetCalories of a missing recipe: got %v, want ErrNotFound", err)
+	}
+
+	low, high := 120, 200
+	tests := []struct {
+		name string
+		q    ListQuery
+		want []string
+	}{
+		{"ascending", ListQuery{Sort: SortCalories}, []string{"eclairs 100 v1", "crepes 150 v1", "stew 150 v1", "broth 250 v2"}},
+		{"descending", ListQuery{Sort: SortCalories, Desc: true, Limit: 2}, []string{"broth 250 v2", "stew 150 v1"}},
+		{"after a tie", ListQuery{Sort: SortCalories, After: &Position{Calories: 150, ID: ids[2]}}, []string{"stew 150 v1", "broth 250 v2"}},
+		{"range", ListQuery{Sort: SortName, MinCalories: &low, MaxCalories: &high}, []string{"crepes 150 v1", "stew 150 v1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := s.Recipes().Query(ctx, tt.q)
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			var got []string
+			for _, recipe := range results {
+				got = append(got, fmt.Sprintf("%s %d v%d", recipe.Name, recipe.CaloriesPerServing, recipe.Version))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Query = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func testIngredientVersionedDelete(t *testing.T, s Store) {
+	ctx := context.Background()
+	id := insertIngredients(t, s, "flour")[0]
+	name := "wholemeal flour"
+	if _, err := s.Ingredients().UpdateByID(ctx, id, 1, model.IngredientUpdate{Name: &name}); err != nil {
+		t.Fatalf("UpdateByID: %v", err)
+	}
+
+	if err := s.Ingredients().DeleteByID(ctx, id, 1); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("DeleteByID at a stale version: got %v, want ErrVersionConflict", err)
+	}
+	if _, err := s.Ingredients().FindByID(ctx, id); err != nil {
+		t.Errorf("stale delete removed the ingredient: %v", err)
+	}
+	if err := s.Ingredients().DeleteByID(ctx, id, 2); err != nil {
+		t.Errorf("DeleteByID at the current version: %v", err)
+	}
+	if _, err := s.Ingredients().FindByID(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindByID after DeleteByID: got %v, want ErrNotFound", err)
+	}
+}
+
+func testRecipeVersionedDelete(t *testing.T, s Store) {
+	ctx := context.Background()
+	id := insertRecipe(t, s, "pancakes", insertIngredients(t, s, "flour")...)
+	servings := 6
+	if _, err := s.Recipes().UpdateByID(ctx, id, 1, model.RecipeUpdate{Servings: &servings}); err != nil {
+		t.Fatalf("UpdateByID: %v", err)
+	}
+
+	if err := s.Recipes().DeleteByID(ctx, id, 1); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("DeleteByID at a stale version: got %v, want ErrVersionConflict", err)
+	}
+	if _, err := s.Recipes().FindByID(ctx, id); err != nil {
+		t.Errorf("stale delete removed the recipe: %v", err)
+	}
+	if err := s.Recipes().DeleteByID(ctx, id, 2); err != nil {
+		t.Errorf("DeleteByID at the current version: %v", err)
+	}
+	if _, err := s.Recipes().FindByID(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindByID after DeleteByID: got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,181 @@
+package units
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	// ErrUnknownUnit is returned for unit names Lookup does not recognise.
+	ErrUnknownUnit = errors.New("unknown unit")
+	// ErrIncompatible is returned when an amount cannot be moved between
+	// dimensions, usually because the ingredient lacks density data.
+	ErrIncompatible = errors.New("incompatible units")
+)
+
+// Density holds the per-ingredient data needed to convert between mass,
+// volume and count. Zero values mean "unknown".
+type Density struct {
+	GramsPerML    float64
+	GramsPerPiece float64
+}
+
+// Convert converts q from one unit to another, crossing dimensions through
+// the ingredient density when needed, e.g. 1 "cup" of flour to "g".
+func Convert(q float64, from, to string, d Density) (float64, error) {
+	fromUnit, ok := Lookup(from)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownUnit, from)
+	}
+	toUnit, ok := Lookup(to)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownUnit, to)
+	}
+
+	base, err := toDimension(q, fromUnit, toUnit.Dimension, d)
+	if err != nil {
+		return 0, err
+	}
+	return base / toUnit.Factor, nil
+}
+
+// ToGrams is shorthand for converting an amount into grams, which is what the
+// per-gram nutrition data is expressed in.
+func ToGrams(q float64, unit string, d Density) (float64, error) {
+	return Convert(q, unit, Gram.Symbol, d)
+}
+
+// ToSystem expresses an amount in the most readable unit of the given system.
+// When dim is non-empty the amount is first moved into that dimension, so
+// "1 cup" of flour can become grams; otherwise the dimension is kept.
+func ToSystem(q float64, unit string, system System, dim Dimension, d Density) (float64, Unit, error) {
+	u, ok := Lookup(unit)
+	if !ok {
+		return 0, Unit{}, fmt.Errorf("%w: %q", ErrUnknownUnit, unit)
+	}
+	if dim == "" {
+		dim = u.Dimension
+	}
+
+	base, err := toDimension(q, u, dim, d)
+	if err != nil {
+		return 0, Unit{}, err
+	}
+	target := Best(base, dim, system)
+	return base / target.Factor, target, nil
+}
+
+// Best picks the unit of the given system that reads most naturally for an
+// amount of base units (grams, millilitres or pieces).
+func Best(base float64, dim Dimension, system System) Unit {
+	switch dim {
+	case Mass:
+		if system == Imperial {
+			if base >= Pound.Factor {
+				return Pound
+			}
+			return Ounce
+		}
+		switch {
+		case base >= Kilogram.Factor:
+			return Kilogram
+		case base > 0 && base < Gram.Factor:
+			return Milligram
+		}
+		return Gram
+	case Volume:
+		if system == Imperial {
+			switch {
+			case base < Tablespoon.Factor:
+				return Teaspoon
+			case base < Cup.Factor/4:
+				return Tablespoon
+			}
+			return Cup
+		}
+		if base >= Litre.Factor {
+			return Litre
+		}
+		return Millilitre
+	}
+	return Piece
+}
+
+// toDimension converts q of u into the base unit of dim.
+func toDimension(q float64, u Unit, dim Dimension, d Density) (float64, error) {
+	base := q * u.Factor
+	if u.Dimension == dim {
+		return base, nil
+	}
+
+	// Everything crosses dimensions through grams.
+	var grams float64
+	switch {
+	case u.Dimension == Mass:
+		grams = base
+	case u.Dimension == Volume && d.GramsPerML > 0:
+		grams = base * d.GramsPerML
+	case u.Dimension == Count && d.GramsPerPiece > 0:
+		grams = base * d.GramsPerPiece
+	default:
+		return 0, fmt.Errorf("%w: %s to %s needs density data", ErrIncompatible, u.Dimension, dim)
+	}
+
+	switch {
+	case dim == Mass:
+		return grams, nil
+	case dim == Volume && d.GramsPerML > 0:
+		return grams / d.GramsPerML, nil
+	case dim == Count && d.GramsPerPiece > 0:
+		return grams / d.GramsPerPiece, nil
+	}
+	return 0, fmt.Errorf("%w: %s to %s needs density data", ErrIncompatible, u.Dimension, dim)
+}
+
+// Round rounds an amount to a precision that makes sense for its unit: whole
+// and half pieces, eighths of a teaspoon, quarter cups, 5 g steps for large
+// weights and so on. Positive amounts are never rounded down to zero.
+func Round(q float64, u Unit) float64 {
+	if q <= 0 {
+		return q
+	}
+
+	var step float64
+	switch u {
+	case Piece:
+		step = 0.5
+	case Teaspoon:
+		step = 0.125
+	case Tablespoon:
+		step = 0.5
+	case Cup:
+		step = 0.25
+	case Ounce, FluidOunce:
+		step = 0.25
+		if q >= 4 {
+			step = 0.5
+		}
+	case Gram, Millilitre:
+		switch {
+		case q < 10:
+			step = 0.1
+		case q < 100:
+			step = 1
+		default:
+			step = 5
+		}
+	case Milligram:
+		step = 1
+	default:
+		step = 0.01
+	}
+
+	rounded := math.Round(q/step) * step
+	if rounded == 0 {
+		// Too small for the unit's usual step; keep two significant digits.
+		magnitude := math.Pow(10, math.Floor(math.Log10(q))-1)
+		rounded = math.Round(q/magnitude) * magnitude
+	}
+	return rounded
+}
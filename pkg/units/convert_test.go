@@ -0,0 +1,92 @@
+package units
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	flour := Density{GramsPerML: 0.53}
+	egg := Density{GramsPerPiece: 50}
+	tests := []struct {
+		name     string
+		q        float64
+		from, to string
+		density  Density
+		want     float64
+		err      error
+	}{
+		{name: "kg to g", q: 1.5, from: "kg", to: "g", want: 1500},
+		{name: "g to mg", q: 2, from: "g", to: "mg", want: 2000},
+		{name: "lb to oz", q: 1, from: "lb", to: "oz", want: 16},
+		{name: "oz to g", q: 4, from: "ounces", to: "grams", want: 113.3980925},
+		{name: "l to ml", q: 0.25, from: "l", to: "ml", want: 250},
+		{name: "cup to tbsp", q: 1, from: "cup", to: "tbsp", want: 16},
+		{name: "tbsp to tsp", q: 2, from: "Tablespoons", to: " tsp ", want: 6},
+		{name: "gallon to quart", q: 1, from: "gal", to: "qt", want: 4},
+		{name: "same unit", q: 3, from: "pc", to: "pieces", want: 3},
+		{name: "volume to mass with density", q: 1, from: "cup", to: "g", density: flour, want: 125.391765345},
+		{name: "mass to volume with density", q: 53, from: "g", to: "ml", density: flour, want: 100},
+		{name: "count to mass with density", q: 2, from: "each", to: "g", density: egg, want: 100},
+		{name: "mass to volume without density", q: 100, from: "g", to: "ml", err: ErrIncompatible},
+		{name: "volume to mass without density", q: 1, from: "cup", to: "oz", err: ErrIncompatible},
+		{name: "volume to mass with piece weight only", q: 1, from: "cup", to: "g", density: egg, err: ErrIncompatible},
+		{name: "count to volume without density", q: 1, from: "pc", to: "ml", err: ErrIncompatible},
+		{name: "unknown source unit", q: 1, from: "handful", to: "g", err: ErrUnknownUnit},
+		{name: "unknown target unit", q: 1, from: "g", to: "pinch", err: ErrUnknownUnit},
+		{name: "empty unit", q: 1, from: "", to: "g", err: ErrUnknownUnit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Convert(tt.q, tt.from, tt.to, tt.density)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Convert(%v, %q, %q) error = %v, want %v", tt.q, tt.from, tt.to, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Convert(%v, %q, %q): %v", tt.q, tt.from, tt.to, err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Convert(%v, %q, %q) = %v, want %v", tt.q, tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		q    float64
+		unit Unit
+		want float64
+	}{
+		{1.3, Piece, 1.5},
+		{0.3, Teaspoon, 0.25},
+		{1.2, Tablespoon, 1},
+		{0.6, Cup, 0.5},
+		{3.1, Ounce, 3},
+		{5.3, Ounce, 5.5},
+		{7.26, Gram, 7.3},
+		{42.4, Gram, 42},
+		{123, Gram, 125},
+		{247.4, Millilitre, 245},
+		{2.4, Milligram, 2},
+		{1.234, Litre, 1.23},
+		// Positive amounts below the usual step keep two significant digits.
+		{0.03, Gram, 0.03},
+		{0.0123, Teaspoon, 0.012},
+		{0.2, Piece, 0.2},
+		// Zero and negative amounts are left alone.
+		{0, Gram, 0},
+		{-3.14, Cup, -3.14},
+	}
+
+	for _, tt := range tests {
+		if got := Round(tt.q, tt.unit); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Round(%v, %s) = %v, want %v", tt.q, tt.unit.Symbol, got, tt.want)
+		}
+	}
+}
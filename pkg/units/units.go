@@ -0,0 +1,110 @@
+package units
+
+import (
+	"strings"
+)
+
+// Dimension is the physical quantity a unit measures.
+type Dimension string
+
+const (
+	Mass   Dimension = "mass"
+	Volume Dimension = "volume"
+	Count  Dimension = "count"
+)
+
+// System is a family of units a recipe can be expressed in.
+type System string
+
+const (
+	Metric   System = "metric"
+	Imperial System = "imperial"
+)
+
+// Unit describes a unit of measure relative to the base unit of its dimension:
+// grams for mass, millilitres for volume and pieces for count.
+type Unit struct {
+	Symbol    string
+	Dimension Dimension
+	System    System
+	// Factor is how many base units one of this unit is.
+	Factor float64
+}
+
+// Volume units are US customary, which is what most imported recipes use.
+var (
+	Gram       = Unit{Symbol: "g", Dimension: Mass, System: Metric, Factor: 1}
+	Kilogram   = Unit{Symbol: "kg", Dimension: Mass, System: Metric, Factor: 1000}
+	Milligram  = Unit{Symbol: "mg", Dimension: Mass, System: Metric, Factor: 0.001}
+	Ounce      = Unit{Symbol: "oz", Dimension: Mass, System: Imperial, Factor: 28.349523125}
+	Pound      = Unit{Symbol: "lb", Dimension: Mass, System: Imperial, Factor: 453.59237}
+	Millilitre = Unit{Symbol: "ml", Dimension: Volume, System: Metric, Factor: 1}
+	Litre      = Unit{Symbol: "l", Dimension: Volume, System: Metric, Factor: 1000}
+	Teaspoon   = Unit{Symbol: "tsp", Dimension: Volume, System: Imperial, Factor: 4.92892159375}
+	Tablespoon = Unit{Symbol: "tbsp", Dimension: Volume, System: Imperial, Factor: 14.78676478125}
+	FluidOunce = Unit{Symbol: "fl oz", Dimension: Volume, System: Imperial, Factor: 29.5735295625}
+	Cup        = Unit{Symbol: "cup", Dimension: Volume, System: Imperial, Factor: 236.5882365}
+	Pint       = Unit{Symbol: "pt", Dimension: Volume, System: Imperial, Factor: 473.176473}
+	Quart      = Unit{Symbol: "qt", Dimension: Volume, System: Imperial, Factor: 946.352946}
+	Gallon     = Unit{Symbol: "gal", Dimension: Volume, System: Imperial, Factor: 3785.411784}
+	Piece      = Unit{Symbol: "pc", Dimension: Count, Factor: 1}
+)
+
+// aliases maps every accepted spelling (lower case) to its unit.
+var aliases = map[string]Unit{}
+
+func init() {
+	register(Gram, "gram", "grams", "gr")
+	register(Kilogram, "kilogram", "kilograms", "kgs")
+	register(Milligram, "milligram", "milligrams")
+	register(Ounce, "ounce", "ounces")
+	register(Pound, "pound", "pounds", "lbs")
+	register(Millilitre, "millilitre", "millilitres", "milliliter", "milliliters")
+	register(Litre, "litre", "litres", "liter", "liters")
+	register(Teaspoon, "teaspoon", "teaspoons")
+	register(Tablespoon, "tablespoon", "tablespoons", "tbs")
+	register(FluidOunce, "floz", "fl. oz", "fluid ounce", "fluid ounces")
+	register(Cup, "cups", "c")
+	register(Pint, "pint", "pints")
+	register(Quart, "quart", "quarts")
+	register(Gallon, "gallon", "gallons")
+	register(Piece, "pcs", "piece", "pieces", "whole", "each", "x")
+}
+
+func register(u Unit, names ...string) {
+	aliases[strings.ToLower(u.Symbol)] = u
+	for _, name := range names {
+		aliases[strings.ToLower(name)] = u
+	}
+}
+
+// Lookup resolves a unit by symbol or common spelling, ignoring case and
+// surrounding whitespace.
+func Lookup(symbol string) (Unit, bool) {
+	u, ok := aliases[strings.ToLower(strings.TrimSpace(symbol))]
+	return u, ok
+}
+
+// ParseSystem validates a unit system name.
+func ParseSystem(name string) (System, bool) {
+	switch System(strings.ToLower(strings.TrimSpace(name))) {
+	case Metric:
+		return Metric, true
+	case Imperial:
+		return Imperial, true
+	}
+	return "", false
+}
+
+// ParseDimension validates a dimension name.
+func ParseDimension(name string) (Dimension, bool) {
+	switch Dimension(strings.ToLower(strings.TrimSpace(name))) {
+	case Mass:
+		return Mass, true
+	case Volume:
+		return Volume, true
+	case Count:
+		return Count, true
+	}
+	return "", false
+}